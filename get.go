@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -15,14 +16,44 @@ var (
 	ErrCannotSetField     = errors.New("cannot set field, field must be exported")
 	ErrUnsupportedType    = errors.New("unsupported type")
 	ErrCannotCast         = errors.New("environment variable cannot be cast to target type")
+	ErrEmpty              = errors.New("environment variable is empty")
 )
 
+// GetOption configures how Get, GetOrDefault, GetOrPanic and GetStruct treat
+// the variables they retrieve.
+type GetOption func(*getOptions)
+
+type getOptions struct {
+	emptyAsUnset bool
+	emptyAsError bool
+}
+
+// EmptyAsUnset treats a variable that is set to an empty value, as in KEY=,
+// as if it was not set, so that Get returns ErrNotSet.
+func EmptyAsUnset() GetOption {
+	return func(o *getOptions) {
+		o.emptyAsUnset = true
+	}
+}
+
+// EmptyAsError makes Get return ErrEmpty for a variable that is set to an
+// empty value, as in KEY=.
+func EmptyAsError() GetOption {
+	return func(o *getOptions) {
+		o.emptyAsError = true
+	}
+}
+
 // Get retrieves an environment variable from the current process.
 //
 // Returns the value cast to the given type parameter or an error if
 // the variable is not set or cannot be cast to the given type.
 //
-// All simple types are supported.
+// A variable set to an empty value is cast like any other value, unless
+// EmptyAsUnset or EmptyAsError is passed.
+//
+// All simple types are supported, as well as slices of string, bool, int,
+// int64 and float64, which are read from comma-separated values.
 //
 // See GetStruct for loading variables into a struct.
 //
@@ -41,11 +72,24 @@ var (
 //	} else if errors.Is(err, ErrCannotCast) {
 //	   ...
 //	}
-func Get[T any](key string) (value T, err error) {
+//
+//	featureX, err := Get[string]("FEATURE_X", EmptyAsError())
+//	if errors.Is(err, ErrEmpty) {
+//	   ...
+//	}
+func Get[T any](key string, opts ...GetOption) (value T, err error) {
+	var o getOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	raw, ok := os.LookupEnv(key)
-	if !ok {
+	if !ok || (raw == "" && o.emptyAsUnset) {
 		return value, fmt.Errorf("genv: %w: %q", ErrNotSet, key)
 	}
+	if raw == "" && o.emptyAsError {
+		return value, fmt.Errorf("genv: %w: %q", ErrEmpty, key)
+	}
 
 	return cast[T](key, raw)
 }
@@ -59,8 +103,8 @@ func Get[T any](key string) (value T, err error) {
 //
 //	secret := GetOrDefault[string]("SECRET_KEY", "super-secret-key")
 //	timeoutMillis := GetOrDefault[int]("TIMEOUT_MILLIS", 500)
-func GetOrDefault[T any](key string, fallback T) (value T) {
-	value, err := Get[T](key)
+func GetOrDefault[T any](key string, fallback T, opts ...GetOption) (value T) {
+	value, err := Get[T](key, opts...)
 	if err != nil {
 		return fallback
 	}
@@ -75,8 +119,8 @@ func GetOrDefault[T any](key string, fallback T) (value T) {
 // Use:
 //
 //	secret := GetOrPanic[string]("SECRET_KEY")
-func GetOrPanic[T any](key string) (value T) {
-	value, err := Get[T](key)
+func GetOrPanic[T any](key string, opts ...GetOption) (value T) {
+	value, err := Get[T](key, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -93,6 +137,8 @@ func GetOrPanic[T any](key string) (value T) {
 // Returns an error if the variable is not set, the argument is not a struct,
 // the tagged field is not exported, or if the variable cannot be cast to the given type.
 //
+// The options apply to every field, see Get.
+//
 // Use:
 //
 //	type Config struct {
@@ -105,7 +151,7 @@ func GetOrPanic[T any](key string) (value T) {
 //	if err := GetStruct(&cfg); err != nil {
 //	   ...
 //	}
-func GetStruct[T any](value T) (err error) {
+func GetStruct[T any](value T, opts ...GetOption) (err error) {
 	typ := reflect.TypeOf(value)
 	if typ.Kind() != reflect.Ptr {
 		return fmt.Errorf("genv: %w", ErrNotPointer)
@@ -132,91 +178,114 @@ func GetStruct[T any](value T) (err error) {
 
 		switch fieldVal.Kind() {
 		case reflect.String:
-			s, err := Get[string](key)
+			s, err := Get[string](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetString(s)
 		case reflect.Bool:
-			b, err := Get[bool](key)
+			b, err := Get[bool](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetBool(b)
 		case reflect.Int:
-			i, err := Get[int](key)
+			i, err := Get[int](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetInt(int64(i))
 		case reflect.Int8:
-			i, err := Get[int8](key)
+			i, err := Get[int8](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetInt(int64(i))
 		case reflect.Int16:
-			i, err := Get[int16](key)
+			i, err := Get[int16](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetInt(int64(i))
 		case reflect.Int32:
-			i, err := Get[int32](key)
+			i, err := Get[int32](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetInt(int64(i))
 		case reflect.Int64:
-			i, err := Get[int64](key)
+			i, err := Get[int64](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetInt(int64(i))
 		case reflect.Uint:
-			i, err := Get[uint](key)
+			i, err := Get[uint](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetUint(uint64(i))
 		case reflect.Uint8:
-			i, err := Get[uint8](key)
+			i, err := Get[uint8](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetUint(uint64(i))
 		case reflect.Uint16:
-			i, err := Get[uint16](key)
+			i, err := Get[uint16](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetUint(uint64(i))
 		case reflect.Uint32:
-			i, err := Get[uint32](key)
+			i, err := Get[uint32](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetUint(uint64(i))
 		case reflect.Uint64:
-			i, err := Get[uint64](key)
+			i, err := Get[uint64](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetUint(uint64(i))
 		case reflect.Float32:
-			f, err := Get[float32](key)
+			f, err := Get[float32](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetFloat(float64(f))
 		case reflect.Float64:
-			f, err := Get[float64](key)
+			f, err := Get[float64](key, opts...)
 			if err != nil {
 				return err
 			}
 			fieldVal.SetFloat(f)
+		case reflect.Slice:
+			var (
+				s   any
+				err error
+			)
+			switch fieldVal.Interface().(type) {
+			case []string:
+				s, err = Get[[]string](key, opts...)
+			case []bool:
+				s, err = Get[[]bool](key, opts...)
+			case []int:
+				s, err = Get[[]int](key, opts...)
+			case []int64:
+				s, err = Get[[]int64](key, opts...)
+			case []float64:
+				s, err = Get[[]float64](key, opts...)
+			default:
+				return fmt.Errorf("genv: %w: type %s, field %s", ErrUnsupportedType, field.Type, field.Name)
+			}
+			if err != nil {
+				return err
+			}
+			fieldVal.Set(reflect.ValueOf(s))
 		case reflect.Struct:
-			err := GetStruct(fieldVal.Addr().Interface())
+			err := GetStruct(fieldVal.Addr().Interface(), opts...)
 			if err != nil {
 				return err
 			}
@@ -311,7 +380,33 @@ func cast[T any](key, raw string) (value T, err error) {
 			return value, fmt.Errorf("genv: %w: %q %T", ErrCannotCast, key, t)
 		}
 		return any(f).(T), nil
+	case []string:
+		return castSlice[T, string](key, raw)
+	case []bool:
+		return castSlice[T, bool](key, raw)
+	case []int:
+		return castSlice[T, int](key, raw)
+	case []int64:
+		return castSlice[T, int64](key, raw)
+	case []float64:
+		return castSlice[T, float64](key, raw)
 	default:
 		return value, fmt.Errorf("genv: %w: %T", ErrUnsupportedType, t)
 	}
 }
+
+// castSlice casts a comma-separated list, such as a JSON array loaded by the
+// parser, to T, a slice of E. An empty value is an empty slice.
+func castSlice[T, E any](key, raw string) (value T, err error) {
+	values := []E{}
+	if raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			v, err := cast[E](key, strings.TrimSpace(part))
+			if err != nil {
+				return value, err
+			}
+			values = append(values, v)
+		}
+	}
+	return any(values).(T), nil
+}
@@ -3,6 +3,7 @@ package genv
 import (
 	"errors"
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -254,6 +255,61 @@ func TestGet(t *testing.T) {
 	})
 }
 
+func TestGetEmpty(t *testing.T) {
+	var key string = "TEST_GET_EMPTY_KEY"
+
+	os.Setenv(key, "")
+	t.Cleanup(func() { os.Unsetenv(key) })
+
+	t.Run("empty is a value", func(t *testing.T) {
+		got, err := Get[string](key)
+		if err != nil {
+			t.Fatalf("should not errorgot %v", err)
+		}
+		if got != "" {
+			t.Fatalf("want emptygot %s", got)
+		}
+	})
+
+	t.Run("empty as unset", func(t *testing.T) {
+		var want error = ErrNotSet
+
+		_, got := Get[string](key, EmptyAsUnset())
+		if !errors.Is(got, want) {
+			t.Fatalf("want %vgot %v", want, got)
+		}
+
+		if got := GetOrDefault[int](key, 5, EmptyAsUnset()); got != 5 {
+			t.Fatalf("want %dgot %d", 5, got)
+		}
+	})
+
+	t.Run("empty as error", func(t *testing.T) {
+		var want error = ErrEmpty
+
+		_, got := Get[string](key, EmptyAsError())
+		if !errors.Is(got, want) {
+			t.Fatalf("want %vgot %v", want, got)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		type Config struct {
+			Empty string `genv:"TEST_GET_EMPTY_KEY"`
+		}
+
+		var cfg Config
+		if err := GetStruct(&cfg); err != nil {
+			t.Fatalf("should not errorgot %v", err)
+		}
+
+		var want error = ErrEmpty
+		if got := GetStruct(&cfg, EmptyAsError()); !errors.Is(got, want) {
+			t.Fatalf("want %vgot %v", want, got)
+		}
+	})
+}
+
 func TestGetOrDefault(t *testing.T) {
 	os.Setenv("TEST_GET_OR_DEFAULT", "test_get_or_default")
 	t.Cleanup(func() { os.Unsetenv("TEST_GET_OR_DEFAULT") })
@@ -837,4 +893,37 @@ func TestCast(t *testing.T) {
 			t.Fatalf("want %vgot %v", want, got)
 		}
 	})
+
+	t.Run("ok []string", func(t *testing.T) {
+		var input string = "a, b,c"
+		var want = []string{"a", "b", "c"}
+
+		got, err := cast[[]string]("", input)
+		if err != nil {
+			t.Fatalf("should not errorgot %v", err)
+		}
+		if !slices.Equal(want, got) {
+			t.Fatalf("want %vgot %v", want, got)
+		}
+	})
+
+	t.Run("ok empty []int", func(t *testing.T) {
+		got, err := cast[[]int]("", "")
+		if err != nil {
+			t.Fatalf("should not errorgot %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Fatalf("want []got %v", got)
+		}
+	})
+
+	t.Run("not ok []int", func(t *testing.T) {
+		var input string = "80,not an int"
+		var want error = ErrCannotCast
+
+		_, got := cast[[]int]("", input)
+		if !errors.Is(got, want) {
+			t.Fatalf("want %vgot %v", want, got)
+		}
+	})
 }
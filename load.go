@@ -1,6 +1,8 @@
 package genv
 
 import (
+	"io"
+	"io/fs"
 	"os"
 
 	"github.com/brendanjcarlson/genv/parser"
@@ -16,28 +18,118 @@ import (
 //
 // Variables set previously will be OVERRIDDEN if set in a subsequent file.
 //
+// Variables already set in the process environment are NOT overridden, so
+// that FOO=x ./app overrides FOO in the files. References to them in the
+// files, as in ${FOO}, also see the value in the process environment. Use
+// Overload to override them instead.
+//
+// Filenames may be glob patterns, expanded in sorted order, and a filename
+// ending with ? is optional and skipped if it does not exist:
+//
+//	err := genv.Load("config/*.env", ".env.local?")
+//
 // See package 'autoload' to make your life even easier.
 func Load(filenames ...string) error {
-	if err := load(filenames...); err != nil {
-		return err
-	}
-	return nil
+	return NewLoader().Load(filenames...)
 }
 
 // Calls Load and panics if there is an error.
 func LoadOrPanic(filenames ...string) {
-	if err := load(filenames...); err != nil {
+	if err := Load(filenames...); err != nil {
+		panic(err)
+	}
+}
+
+// Overload reads env files and loads the variables into the current process,
+// like Load, but OVERRIDES the variables already set in the process
+// environment.
+//
+// Use it when the files must win, as in tests that load fixtures:
+//
+//	err := genv.Overload("testdata/.env.test")
+func Overload(filenames ...string) error {
+	return (&Loader{Override: true}).Load(filenames...)
+}
+
+// Calls Overload and panics if there is an error.
+func OverloadOrPanic(filenames ...string) {
+	if err := Overload(filenames...); err != nil {
 		panic(err)
 	}
 }
 
-func load(filenames ...string) error {
-	p := parser.NewParser(filenames...)
-	result, err := p.Parse()
+// LoadReader reads variables in the env file format from r and loads them
+// into the current process, like Load.
+func LoadReader(r io.Reader) error {
+	return NewLoader().LoadReader(r)
+}
+
+// LoadFS reads the named env files from fsys and loads the variables into the
+// current process, like Load.
+//
+// Use it to ship default configuration embedded in the binary:
+//
+//	//go:embed .env.defaults
+//	var defaults embed.FS
+//
+//	err := genv.LoadFS(defaults, ".env.defaults")
+func LoadFS(fsys fs.FS, paths ...string) error {
+	return NewLoader().LoadFS(fsys, paths...)
+}
+
+// Loader loads env files with parser options, such as the dialect of the
+// files.
+//
+// Use:
+//
+//	loader := genv.NewLoader(parser.WithDialect(parser.DialectCompose))
+//	loader.Override = true
+//	if err := loader.Load(".env"); err != nil {
+//	    ...
+//	}
+type Loader struct {
+	// Override makes the files override the variables already set in the
+	// process environment, see Overload. By default they are kept.
+	Override bool
+
+	opts []parser.Option
+}
+
+// NewLoader returns a Loader that parses files with the given options.
+func NewLoader(opts ...parser.Option) *Loader {
+	return &Loader{opts: opts}
+}
+
+// Load reads env files and loads the variables into the current process. See
+// the package-level Load.
+func (l *Loader) Load(filenames ...string) error {
+	return l.load(parser.NewParser(filenames...))
+}
+
+// LoadReader reads variables from r and loads them into the current process.
+// See the package-level LoadReader.
+func (l *Loader) LoadReader(r io.Reader) error {
+	return l.load(parser.NewReaderParser("<reader>", r))
+}
+
+// LoadFS reads the named env files from fsys and loads the variables into the
+// current process. See the package-level LoadFS.
+func (l *Loader) LoadFS(fsys fs.FS, paths ...string) error {
+	return l.load(parser.NewFSParser(fsys, paths...))
+}
+
+func (l *Loader) load(p *parser.Parser) error {
+	if !l.Override {
+		p = p.With(parser.WithEnvPrecedence())
+	}
+	result, err := p.With(l.opts...).Parse()
 	if err != nil {
 		return err
 	}
 	for k, v := range result.Vars {
+		if _, ok := os.LookupEnv(k); ok && !l.Override {
+			continue
+		}
 		if err := os.Setenv(k, v); err != nil {
 			return err
 		}
@@ -1,8 +1,15 @@
 package genv
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/brendanjcarlson/genv/parser"
 )
 
 func TestLoadSingleFile(t *testing.T) {
@@ -63,3 +70,274 @@ func TestLoadOrPanic(t *testing.T) {
 		LoadOrPanic("not a real filepath")
 	})
 }
+
+func TestLoadReader(t *testing.T) {
+	t.Cleanup(func() { os.Unsetenv("TEST_LOAD_READER_KEY") })
+
+	if err := LoadReader(strings.NewReader("TEST_LOAD_READER_KEY=from reader\n")); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got, want := os.Getenv("TEST_LOAD_READER_KEY"), "from reader"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_LOAD_FS_KEY")
+		os.Unsetenv("TEST_LOAD_FS_EXPANDED_KEY")
+	})
+
+	fsys := fstest.MapFS{
+		"config/.env":       {Data: []byte("TEST_LOAD_FS_KEY=from fs\n")},
+		"config/.env.local": {Data: []byte("TEST_LOAD_FS_EXPANDED_KEY=${TEST_LOAD_FS_KEY} and more\n")},
+	}
+	if err := LoadFS(fsys, "config/.env", "config/.env.local"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got, want := os.Getenv("TEST_LOAD_FS_EXPANDED_KEY"), "from fs and more"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+
+	if err := LoadFS(fsys, "config/missing.env"); err == nil {
+		t.Fatalf("should have failed on a missing file")
+	}
+}
+
+func TestLoadEmptyOverride(t *testing.T) {
+	keys := []string{"KEY", "BOOL_KEY", "INT_KEY", "FLOAT_KEY", "EXPANDED_KEY", "MULTI_EXPANDED_KEY"}
+	unset := func() {
+		for _, k := range keys {
+			os.Unsetenv(k)
+		}
+	}
+	// Earlier tests leave these set, and Load keeps variables already set.
+	unset()
+	t.Cleanup(unset)
+
+	if err := Load("./testdata/.env", "./testdata/.env.empty"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, k := range []string{"KEY", "BOOL_KEY"} {
+		v, ok := os.LookupEnv(k)
+		if !ok {
+			t.Fatalf("%s was not set", k)
+		}
+		if v != "" {
+			t.Fatalf("want %s to be empty, got %s", k, v)
+		}
+	}
+}
+
+func TestLoaderDialect(t *testing.T) {
+	t.Cleanup(func() { os.Unsetenv("TEST_LOADER_KEY") })
+
+	loader := NewLoader(parser.WithDialect(parser.DialectDocker))
+	if err := loader.LoadReader(strings.NewReader("TEST_LOADER_KEY=\"quoted\" # kept\n")); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got, want := os.Getenv("TEST_LOADER_KEY"), `"quoted" # kept`; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	t.Cleanup(func() {
+		for _, k := range []string{"NAME", "PORT", "RATIO", "DEBUG", "TOKEN", "DB_HOST", "DB_MAX_CONNS", "TAGS", "PORTS", "SERVERS_0_HOST", "SERVERS_1_HOST"} {
+			os.Unsetenv(k)
+		}
+	})
+
+	if err := Load("./testdata/json/config.json"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	port, err := Get[int]("PORT")
+	if err != nil || port != 8080 {
+		t.Fatalf("want 8080, got %d: %v", port, err)
+	}
+	ports, err := Get[[]int]("PORTS")
+	if err != nil || len(ports) != 2 || ports[1] != 443 {
+		t.Fatalf("want [80 443], got %v: %v", ports, err)
+	}
+	var cfg struct {
+		Host string   `genv:"DB_HOST"`
+		Tags []string `genv:"TAGS"`
+	}
+	if err := GetStruct(&cfg); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if cfg.Host != "localhost" || strings.Join(cfg.Tags, " ") != "a b c" {
+		t.Fatalf("want localhost [a b c], got %+v", cfg)
+	}
+}
+
+func TestLoadPropertiesAndINI(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_LOAD_PROPS_KEY")
+		os.Unsetenv("TEST_LOAD_INI_KEY")
+	})
+
+	fsys := fstest.MapFS{
+		"app.properties": {Data: []byte("test.load.props.key=from properties\ntest.load.ini.key=overridden\n")},
+		"app.ini":        {Data: []byte("[test_load_ini]\nkey = from ini\n")},
+	}
+	if err := LoadFS(fsys, "app.properties", "app.ini"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got, want := os.Getenv("TEST_LOAD_PROPS_KEY"), "from properties"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+	if got, want := os.Getenv("TEST_LOAD_INI_KEY"), "from ini"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestLoadSystemdAndEnvdir(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_LOAD_SYSTEMD_KEY")
+		os.Unsetenv("TEST_LOAD_ENVDIR_KEY")
+	})
+
+	loader := NewLoader(parser.WithFormat(parser.FormatSystemd))
+	if err := loader.LoadReader(strings.NewReader("TEST_LOAD_SYSTEMD_KEY=\"a\" 'b' \\\n  c # d\n")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got, want := os.Getenv("TEST_LOAD_SYSTEMD_KEY"), "ab  c # d"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+
+	fsys := fstest.MapFS{"secrets/TEST_LOAD_ENVDIR_KEY": {Data: []byte("from envdir\n")}}
+	if err := LoadFS(fsys, "secrets"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got, want := os.Getenv("TEST_LOAD_ENVDIR_KEY"), "from envdir"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestLoaderRequireBareKeys(t *testing.T) {
+	loader := NewLoader(parser.WithRequireBareKeys())
+	err := loader.LoadReader(strings.NewReader("GENV_TEST_MISSING_BARE_KEY\n"))
+	if !errors.Is(err, parser.ErrBareKeyNotSet) {
+		t.Fatalf("want %v, got %v", parser.ErrBareKeyNotSet, err)
+	}
+}
+
+func TestLoadGlobAndOptional(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv("NAME")
+		os.Unsetenv("PORT")
+	})
+
+	if err := Load("./testdata/glob/config/*.env", "./testdata/glob/.env.missing?"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got, want := os.Getenv("NAME"), "base"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+
+	if err := Load("./testdata/glob/.env.missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("want %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestLoadKeepsProcessEnv(t *testing.T) {
+	t.Setenv("TEST_LOAD_PRECEDENCE_KEY", "from process")
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_LOAD_PRECEDENCE_REF")
+		os.Unsetenv("TEST_LOAD_PRECEDENCE_NEW")
+	})
+
+	content := "TEST_LOAD_PRECEDENCE_KEY=from file\n" +
+		"TEST_LOAD_PRECEDENCE_REF=${TEST_LOAD_PRECEDENCE_KEY}\n" +
+		"TEST_LOAD_PRECEDENCE_NEW=new\n"
+	if err := LoadReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := map[string]string{
+		"TEST_LOAD_PRECEDENCE_KEY": "from process",
+		"TEST_LOAD_PRECEDENCE_REF": "from process",
+		"TEST_LOAD_PRECEDENCE_NEW": "new",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Fatalf("want %s=%s, got %s=%s", k, v, k, got)
+		}
+	}
+}
+
+func TestOverload(t *testing.T) {
+	t.Setenv("KEY", "from process")
+	t.Cleanup(func() {
+		for _, k := range []string{"BOOL_KEY", "INT_KEY", "FLOAT_KEY", "EXPANDED_KEY", "MULTI_EXPANDED_KEY"} {
+			os.Unsetenv(k)
+		}
+	})
+
+	if err := Overload("./testdata/.env"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := map[string]string{
+		"KEY":          "value",
+		"EXPANDED_KEY": "foo value",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Fatalf("want %s=%s, got %s=%s", k, v, k, got)
+		}
+	}
+
+	loader := NewLoader()
+	loader.Override = true
+	if err := loader.LoadReader(strings.NewReader("KEY=from loader\n")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got, want := os.Getenv("KEY"), "from loader"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestLoadSelfReference(t *testing.T) {
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_SELF_PORT")
+		os.Unsetenv("TEST_SELF_HOST")
+	})
+	content := "TEST_SELF_PORT=${TEST_SELF_PORT:-8080}\nTEST_SELF_HOST=${TEST_SELF_HOST:-localhost}\n"
+
+	name := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	os.Setenv("TEST_SELF_HOST", "from process")
+	if err := Overload(name); err != nil {
+		t.Fatalf("%v", err)
+	}
+	want := map[string]string{
+		"TEST_SELF_PORT": "8080",
+		"TEST_SELF_HOST": "from process",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Fatalf("want %s=%s, got %s=%s", k, v, k, got)
+		}
+	}
+}
+
+func TestOverloadOrPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("should have panicked")
+		}
+	}()
+
+	OverloadOrPanic("not a real filepath")
+}
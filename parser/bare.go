@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBareKeyNotSet is returned by Parser.Parse for a bare key that is not set
+// in the environment, when WithRequireBareKeys is passed.
+var ErrBareKeyNotSet = errors.New("bare key not set in the environment")
+
+// WithRequireBareKeys makes Parser.Parse fail on a bare key that is not set in
+// the environment.
+//
+// A bare key is a line with a key and no =, as in
+//
+//	DATABASE_URL
+//
+// which declares the key with its value in the environment, as found by the
+// lookup function, like docker and compose do. It documents that the file
+// depends on the variable. By default a bare key that is not set in the
+// environment is skipped.
+func WithRequireBareKeys() Option {
+	return func(p *Parser) {
+		p.requireBare = true
+	}
+}
+
+// declareBare declares key with its value in the environment. The value is
+// not expanded.
+func (p *Parser) declareBare(key, file string, line, column int) error {
+	var (
+		value string
+		ok    bool
+	)
+	if p.lookup != nil {
+		value, ok = p.lookup(key)
+	}
+	if !ok {
+		if p.requireBare {
+			return fmt.Errorf("%w: %q", ErrBareKeyNotSet, key)
+		}
+		return nil
+	}
+	p.declareValue(key, value, file, line, column)
+	return nil
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParserBareKeys(t *testing.T) {
+	env := map[string]string{
+		"HOST_DEP": "db.internal",
+		"EMPTY":    "",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	content := "# Variables passed through from the host.\n" +
+		"export HOST_DEP # the database host\n" +
+		"EMPTY  \n" +
+		"  MISSING\n" +
+		"URL=http://${HOST_DEP}:${PORT:-5432}\n"
+
+	t.Run("pass through", func(t *testing.T) {
+		result, err := NewParser(writeEnv(t, content)).With(WithLookup(lookup)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		want := map[string]string{
+			"HOST_DEP": "db.internal",
+			"EMPTY":    "",
+			"URL":      "http://db.internal:5432",
+		}
+		assertVars(t, result, want)
+		if e, _ := result.Lookup("HOST_DEP"); e.Line != 2 || e.Column != 8 {
+			t.Fatalf("want 2:8, got %d:%d", e.Line, e.Column)
+		}
+	})
+
+	t.Run("required", func(t *testing.T) {
+		_, err := NewParser(writeEnv(t, content)).With(WithLookup(lookup), WithRequireBareKeys()).Parse()
+		if !errors.Is(err, ErrBareKeyNotSet) {
+			t.Fatalf("want %v, got %v", ErrBareKeyNotSet, err)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Line != 4 || perr.Column != 3 {
+			t.Fatalf("want error at 4:3, got %v", err)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		result, err := NewParser(writeEnv(t, "HOST_DEP=default\nHOST_DEP\n")).With(WithLookup(lookup)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		e, _ := result.Lookup("HOST_DEP")
+		if e.Value != "db.internal" || len(e.Overrides) != 1 {
+			t.Fatalf("want db.internal overriding default, got %+v", e)
+		}
+	})
+}
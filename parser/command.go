@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrCommandNotAllowed is returned by Parser.Parse when the allow function
+// passed to WithCommands rejects a command.
+var ErrCommandNotAllowed = errors.New("command not allowed")
+
+type commandConfig struct {
+	timeout time.Duration
+	allow   func(command string) bool
+}
+
+// WithCommands enables command substitution, as in GIT_SHA=$(git rev-parse HEAD).
+// It is disabled by default, and $(command) is kept as written.
+//
+// Commands run with sh -c in the current process environment and are killed
+// after timeout, a timeout of 0 means no limit. Their output replaces the
+// reference, without trailing newlines.
+//
+// If allow is not nil, it is called with every command before it runs, and
+// commands it rejects fail with ErrCommandNotAllowed.
+func WithCommands(timeout time.Duration, allow func(command string) bool) Option {
+	return func(p *Parser) {
+		p.commands = &commandConfig{timeout: timeout, allow: allow}
+	}
+}
+
+// runCommand returns the output of command, or ref when command substitution
+// is disabled. The error of a failed command includes its stderr.
+func (p *Parser) runCommand(ref, command string) (string, error) {
+	if p.commands == nil {
+		return ref, nil
+	}
+	if p.commands.allow != nil && !p.commands.allow(command) {
+		return "", fmt.Errorf("%w: %q", ErrCommandNotAllowed, command)
+	}
+
+	ctx := context.Background()
+	if p.commands.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.commands.timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Commands that leave children behind holding the output open must not
+	// outlive the timeout.
+	cmd.WaitDelay = 100 * time.Millisecond
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %q: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("command %q: %w", command, err)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
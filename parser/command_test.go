@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParserCommands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	content := "GREETING=$(printf 'hello\\n\\n')\n" +
+		"SENTENCE=\"${GREETING}, $(echo world)\"\n" +
+		"LITERAL='$(echo literal)'\n" +
+		"ESCAPED=\\$(echo escaped)\n"
+
+	t.Run("disabled", func(t *testing.T) {
+		result, err := NewParser(writeEnv(t, content)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got, want := result.Vars["SENTENCE"], `$(printf 'hello\n\n'), $(echo world)`; got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		result, err := NewParser(writeEnv(t, content)).With(WithCommands(time.Second, nil)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		want := map[string]string{
+			`GREETING`: `hello`,
+			`SENTENCE`: `hello, world`,
+			`LITERAL`:  `$(echo literal)`,
+			`ESCAPED`:  `$(echo escaped)`,
+		}
+		for wantKey, wantValue := range want {
+			if got := result.Vars[wantKey]; got != wantValue {
+				t.Fatalf("%s: want %q, got %q", wantKey, wantValue, got)
+			}
+		}
+	})
+
+	t.Run("parens", func(t *testing.T) {
+		content := "QUOTED=$(printf 'a)b')\n" +
+			"NESTED=\"$(echo $(echo inner))\"\n" +
+			"DATE=$(echo $(date))\n"
+		result, err := NewParser(writeEnv(t, content)).With(WithCommands(time.Second, nil)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		want := map[string]string{
+			`QUOTED`: `a)b`,
+			`NESTED`: `inner`,
+		}
+		for wantKey, wantValue := range want {
+			if got := result.Vars[wantKey]; got != wantValue {
+				t.Fatalf("%s: want %q, got %q", wantKey, wantValue, got)
+			}
+		}
+		if got := result.Vars["DATE"]; got == "" || strings.Contains(got, ")") {
+			t.Fatalf("want the date, got %q", got)
+		}
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		allow := func(command string) bool {
+			return strings.HasPrefix(command, "echo ")
+		}
+		_, err := NewParser(writeEnv(t, content)).With(WithCommands(time.Second, allow)).Parse()
+		if !errors.Is(err, ErrCommandNotAllowed) {
+			t.Fatalf("want %v, got %v", ErrCommandNotAllowed, err)
+		}
+		if !strings.Contains(err.Error(), "printf") {
+			t.Fatalf("want the command in the error, got %v", err)
+		}
+	})
+
+	t.Run("stderr", func(t *testing.T) {
+		name := writeEnv(t, "FAILS=$(echo 'something broke' >&2; exit 3)\n")
+		_, err := NewParser(name).With(WithCommands(time.Second, nil)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("want *ParseError, got %v", err)
+		}
+		if perr.Line != 1 || !strings.Contains(err.Error(), "something broke") {
+			t.Fatalf("want stderr at line 1, got %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		name := writeEnv(t, "SLOW=$(sleep 5)\n")
+		start := time.Now()
+		_, err := NewParser(name).With(WithCommands(50*time.Millisecond, nil)).Parse()
+		if err == nil {
+			t.Fatalf("want error")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("want timeout, took %v", elapsed)
+		}
+	})
+}
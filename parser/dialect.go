@@ -0,0 +1,139 @@
+package parser
+
+// A Dialect describes the env file syntax of a particular tool, so that files
+// shared with that tool load into the same values.
+//
+// Use one of the presets with WithDialect:
+//
+//	p := parser.NewParser(".env").With(parser.WithDialect(parser.DialectDocker))
+type Dialect struct {
+	// Export strips a leading export keyword from keys.
+	Export bool
+	// Quotes unquotes values in single and double quotes. Without it values
+	// are taken verbatim.
+	Quotes bool
+	// Backticks also unquotes values in backticks.
+	Backticks bool
+	// Escapes decides which escapes are processed in double-quoted values.
+	Escapes Escapes
+	// Expand expands references in values that are not single-quoted or in
+	// backticks.
+	Expand bool
+	// InlineComments decides where a comment after a value starts.
+	InlineComments InlineComments
+	// TrimSpace trims the whitespace around = and around unquoted values.
+	TrimSpace bool
+	// Heredocs reads values given as heredocs, as in KEY<<EOF.
+	Heredocs bool
+	// KeyGrammar decides which keys are valid.
+	KeyGrammar KeyGrammar
+	// Undefined decides what happens to references to undefined keys.
+	Undefined UndefinedPolicy
+}
+
+// Escapes decides which escapes are processed in double-quoted values.
+type Escapes int
+
+const (
+	// EscapesNone takes backslashes literally.
+	EscapesNone Escapes = iota
+	// EscapesNewlines only processes \n and \r.
+	EscapesNewlines
+	// EscapesShell processes \n, \r, \t, \", \\ and \$. Other backslashes are
+	// taken literally.
+	EscapesShell
+	// EscapesAll processes \n and \r, and replaces any other escaped char
+	// with the char itself.
+	EscapesAll
+)
+
+// InlineComments decides where a comment after a value starts.
+type InlineComments int
+
+const (
+	// InlineCommentsNone takes a # after a value as part of the value.
+	InlineCommentsNone InlineComments = iota
+	// InlineCommentsAfterSpace starts a comment at a # preceded by
+	// whitespace, so that URL=http://x/#frag keeps its fragment.
+	InlineCommentsAfterSpace
+	// InlineCommentsAnyHash starts a comment at any # outside of quotes.
+	InlineCommentsAnyHash
+)
+
+var (
+	// DialectGenv is the default dialect. It reads export prefixes, quotes,
+	// backticks, shell escapes, inline comments after whitespace and heredocs,
+	// expands references and only accepts POSIX keys.
+	DialectGenv = Dialect{
+		Export:         true,
+		Quotes:         true,
+		Backticks:      true,
+		Escapes:        EscapesShell,
+		Expand:         true,
+		InlineComments: InlineCommentsAfterSpace,
+		TrimSpace:      true,
+		Heredocs:       true,
+		KeyGrammar:     KeyGrammarPOSIX,
+		Undefined:      UndefinedError,
+	}
+
+	// DialectDocker reads files like docker run --env-file. Every value is
+	// taken verbatim, including quotes, whitespace and #, and keys may hold
+	// any char but whitespace and =.
+	DialectDocker = Dialect{
+		KeyGrammar: KeyGrammarAny,
+	}
+
+	// DialectCompose reads files like the env_file and .env files of
+	// docker compose, with quotes, inline comments and expansion. Undefined
+	// references expand to an empty string.
+	DialectCompose = Dialect{
+		Export:         true,
+		Quotes:         true,
+		Escapes:        EscapesShell,
+		Expand:         true,
+		InlineComments: InlineCommentsAfterSpace,
+		TrimSpace:      true,
+		KeyGrammar:     KeyGrammarRelaxed,
+		Undefined:      UndefinedEmpty,
+	}
+
+	// DialectNode reads files like the dotenv package for Node.js, without
+	// dotenv-expand. Double-quoted values only process \n and \r, and a #
+	// anywhere outside of quotes starts a comment.
+	DialectNode = Dialect{
+		Export:         true,
+		Quotes:         true,
+		Backticks:      true,
+		Escapes:        EscapesNewlines,
+		InlineComments: InlineCommentsAnyHash,
+		TrimSpace:      true,
+		KeyGrammar:     KeyGrammarRelaxed,
+	}
+
+	// DialectRuby reads files like the dotenv gem for Ruby. Double-quoted
+	// values unescape any char, a # anywhere outside of quotes starts a
+	// comment, and undefined references expand to an empty string. Command
+	// substitution still has to be enabled with WithCommands.
+	DialectRuby = Dialect{
+		Export:         true,
+		Quotes:         true,
+		Escapes:        EscapesAll,
+		Expand:         true,
+		InlineComments: InlineCommentsAnyHash,
+		TrimSpace:      true,
+		KeyGrammar:     KeyGrammarRelaxed,
+		Undefined:      UndefinedEmpty,
+	}
+)
+
+// WithDialect sets the dialect of the parsed files. It also sets the key
+// grammar and the policy for undefined references, which options passed after
+// it may change.
+func WithDialect(dialect Dialect) Option {
+	return func(p *Parser) {
+		p.dialect = dialect
+		p.keyGrammar = dialect.KeyGrammar
+		p.undefined = dialect.Undefined
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDialectCorpus(t *testing.T) {
+	testcases := []struct {
+		name    string
+		dialect Dialect
+	}{
+		{"genv", DialectGenv},
+		{"docker", DialectDocker},
+		{"compose", DialectCompose},
+		{"node", DialectNode},
+		{"ruby", DialectRuby},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := os.ReadFile("../testdata/dialects/" + tc.name + ".json")
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			var want map[string]string
+			if err := json.Unmarshal(b, &want); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			result, err := NewParser("../testdata/dialects/corpus.env").
+				With(WithDialect(tc.dialect), WithLookup(nil)).
+				Parse()
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			assertVars(t, result, want)
+		})
+	}
+}
+
+func TestDialectSyntax(t *testing.T) {
+	t.Run("export", func(t *testing.T) {
+		for _, d := range []Dialect{DialectGenv, DialectCompose, DialectNode, DialectRuby} {
+			result, err := NewParser(writeEnv(t, "export KEY=value\n")).With(WithDialect(d)).Parse()
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if got := result.Vars["KEY"]; got != "value" {
+				t.Fatalf("want %q, got %q", "value", got)
+			}
+		}
+	})
+
+	t.Run("docker keys", func(t *testing.T) {
+		result, err := NewParser(writeEnv(t, "my.key-1=value\n")).With(WithDialect(DialectDocker)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got := result.Vars["my.key-1"]; got != "value" {
+			t.Fatalf("want %q, got %q", "value", got)
+		}
+	})
+
+	t.Run("docker spaces", func(t *testing.T) {
+		_, err := NewParser(writeEnv(t, "KEY = value\n")).With(WithDialect(DialectDocker)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("want *ParseError, got %v", err)
+		}
+		if perr.Line != 1 || perr.Column != 4 {
+			t.Fatalf("want 1:4, got %d:%d", perr.Line, perr.Column)
+		}
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		content := "KEY=${UNDEFINED}\n"
+		if _, err := NewParser(writeEnv(t, content)).With(WithLookup(nil)).Parse(); err == nil {
+			t.Fatalf("genv: want error")
+		}
+		result, err := NewParser(writeEnv(t, content)).With(WithDialect(DialectCompose), WithLookup(nil)).Parse()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got := result.Vars["KEY"]; got != "" {
+			t.Fatalf("compose: want empty, got %q", got)
+		}
+	})
+}
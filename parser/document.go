@@ -0,0 +1,382 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Document is an env file that keeps every line as it was read, including
+// comments, blank lines, ordering and quoting, so that it can be edited and
+// written back. Lines that are not edited are written back byte for byte.
+//
+// Values are handled as declared, unquoted but not expanded, like Entry.Raw.
+//
+// Use:
+//
+//	doc, err := parser.ParseDocument(".env", f)
+//	if err != nil {
+//	    ...
+//	}
+//	if err := doc.Set("API_KEY", newKey); err != nil {
+//	    ...
+//	}
+//	doc.Delete("OLD_KEY")
+//	_, err = doc.WriteTo(w)
+type Document struct {
+	// bom holds the byte order mark the document started with, if any.
+	bom   string
+	lines []*docLine
+	// newline terminates lines added to the document.
+	newline string
+	// keyGrammar decides which keys can be added to the document.
+	keyGrammar KeyGrammar
+}
+
+// docLine is a line of a document, or several lines for a multiline value.
+type docLine struct {
+	// text is the line as read, including its line terminator.
+	text string
+	// entry is nil for blank lines and comments.
+	entry *docEntry
+}
+
+// docEntry is a declaration in a document. The text of its line is split
+// around the key and the value, so that either one can be replaced.
+type docEntry struct {
+	// prefix holds the indentation and the export keyword.
+	prefix string
+	key    string
+	// infix holds the text between the key and the value, as in " = ".
+	infix string
+	// valueText is the value as written, including its quotes.
+	valueText string
+	// suffix holds the trailing whitespace, the inline comment and the line
+	// terminator.
+	suffix string
+	value  string
+	quote  byte
+	// heredoc is set for values given as a heredoc, as in KEY<<EOF.
+	heredoc *heredoc
+}
+
+func (l *docLine) update() {
+	e := l.entry
+	l.text = e.prefix + e.key + e.infix + e.valueText + e.suffix
+}
+
+// ParseDocument reads a document in the env file format from r. The name is
+// used in errors in place of a filename. Options that change how lines are
+// read, such as WithKeyGrammar, apply to the document.
+func ParseDocument(name string, r io.Reader, opts ...Option) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := newParser().With(opts...)
+	d := &Document{newline: "\n", keyGrammar: p.keyGrammar}
+	if bytes.HasPrefix(b, bom) {
+		d.bom = string(bom)
+		b = b[len(bom):]
+	}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	for i := 0; i < len(lines); i++ {
+		text := lines[i]
+		if text == "" {
+			continue
+		}
+		if i == 0 && strings.HasSuffix(text, "\r\n") {
+			d.newline = "\r\n"
+		}
+
+		trimmed := strings.TrimSpace(text)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			d.lines = append(d.lines, &docLine{text: text})
+			continue
+		}
+
+		start := i
+		indent := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+		lp := p.newLineParser(bytes.TrimRight([]byte(text[indent:]), "\r\n"))
+		key, value, err := lp.parse()
+		for errors.Is(err, errUnterminatedQuote) && i+1 < len(lines) && lines[i+1] != "" {
+			i++
+			text += lines[i]
+			lp = p.newLineParser(bytes.TrimRight([]byte(text[indent:]), "\r\n"))
+			key, value, err = lp.parse()
+		}
+		if err == nil && lp.heredoc != nil {
+			value, err = readHeredoc(lp, lines, &i, &text)
+		}
+		if err != nil {
+			return nil, locate(err, name, start+1, indent, bytes.TrimRight([]byte(text), "\r\n"))
+		}
+		value = strings.ReplaceAll(value, "\r\n", "\n")
+
+		e := &docEntry{
+			prefix:    text[:indent+lp.keyStart],
+			key:       key,
+			infix:     text[indent+lp.keyEnd : indent+lp.valueStart],
+			valueText: text[indent+lp.valueStart : indent+lp.valueEnd],
+			suffix:    text[indent+lp.valueEnd:],
+			value:     value,
+			quote:     lp.quote,
+			heredoc:   lp.heredoc,
+		}
+		if e.heredoc != nil {
+			// The value runs to the end of the line with the delimiter.
+			end := len(strings.TrimRight(text, "\r\n"))
+			e.valueText, e.suffix = text[indent+lp.valueStart:end], text[end:]
+		}
+		d.lines = append(d.lines, &docLine{text: text, entry: e})
+	}
+	return d, nil
+}
+
+// Keys returns the declared keys in the order they were first declared.
+func (d *Document) Keys() []string {
+	var (
+		keys []string
+		seen = make(map[string]bool)
+	)
+	for _, l := range d.lines {
+		if l.entry != nil && !seen[l.entry.key] {
+			seen[l.entry.key] = true
+			keys = append(keys, l.entry.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of the last declaration of key. The value of a bare
+// key, as in KEY, is empty.
+func (d *Document) Get(key string) (string, bool) {
+	l := d.last(key)
+	if l == nil {
+		return "", false
+	}
+	return l.entry.value, true
+}
+
+// Set changes the value of the last declaration of key, keeping its quoting
+// style when the value can be written in it. A key that is not declared is
+// added at the end of the document, and must be valid in the key grammar
+// the document was parsed with.
+func (d *Document) Set(key, value string) error {
+	l := d.last(key)
+	if l == nil {
+		if !d.keyGrammar.valid(key) {
+			return fmt.Errorf("set %s: invalid key", key)
+		}
+		valueText, err := quoteValue(value, 0, false)
+		if err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+		if n := len(d.lines); n > 0 && !strings.HasSuffix(d.lines[n-1].text, "\n") {
+			d.lines[n-1].text += d.newline
+			if e := d.lines[n-1].entry; e != nil {
+				e.suffix += d.newline
+			}
+		}
+		l = &docLine{entry: &docEntry{key: key, infix: "=", valueText: valueText, suffix: d.newline, value: value}}
+		l.update()
+		d.lines = append(d.lines, l)
+		return nil
+	}
+
+	multiline := strings.Contains(l.entry.valueText, "\n")
+	valueText, ok := heredocValue(value, l.entry.heredoc)
+	if !ok {
+		if l.entry.heredoc != nil {
+			l.entry.heredoc, l.entry.infix = nil, "="
+		}
+		var err error
+		if valueText, err = quoteValue(value, l.entry.quote, multiline); err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	if multiline {
+		valueText = strings.ReplaceAll(valueText, "\n", d.newline)
+	}
+	l.entry.value = value
+	l.entry.valueText = valueText
+	if l.entry.infix == "" && l.entry.heredoc == nil {
+		// The key was bare, as in KEY.
+		l.entry.infix = "="
+	}
+	l.entry.quote = 0
+	if len(valueText) > 0 && strings.IndexByte("'\"`", valueText[0]) >= 0 {
+		l.entry.quote = valueText[0]
+	}
+	l.update()
+	return nil
+}
+
+// Delete removes every declaration of key and reports whether there was any.
+func (d *Document) Delete(key string) bool {
+	n := len(d.lines)
+	d.lines = slices.DeleteFunc(d.lines, func(l *docLine) bool {
+		return l.entry != nil && l.entry.key == key
+	})
+	return len(d.lines) != n
+}
+
+// Rename renames every declaration of oldKey to newKey. It fails if oldKey is
+// not declared, newKey already is, or newKey is not valid in the key grammar
+// the document was parsed with.
+func (d *Document) Rename(oldKey, newKey string) error {
+	if d.last(oldKey) == nil {
+		return fmt.Errorf("rename %s: key not declared", oldKey)
+	}
+	if d.last(newKey) != nil {
+		return fmt.Errorf("rename %s: key already declared: %s", oldKey, newKey)
+	}
+	if !d.keyGrammar.valid(newKey) {
+		return fmt.Errorf("rename %s: invalid key: %s", oldKey, newKey)
+	}
+	for _, l := range d.lines {
+		if l.entry != nil && l.entry.key == oldKey {
+			l.entry.key = newKey
+			l.update()
+		}
+	}
+	return nil
+}
+
+// Bytes returns the contents of the document.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	d.WriteTo(&b)
+	return b.Bytes()
+}
+
+// WriteTo writes the contents of the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, d.bom)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	for _, l := range d.lines {
+		n, err := io.WriteString(w, l.text)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (d *Document) last(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if l := d.lines[i]; l.entry != nil && l.entry.key == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// quoteValue writes value in the given quoting style, where 0 is unquoted. If
+// the value cannot be written in that style, it is double-quoted, or
+// single-quoted as a last resort. Newlines in double-quoted values are
+// escaped, unless the value is written over multiple lines.
+func quoteValue(value string, quote byte, multiline bool) (string, error) {
+	for _, q := range []byte{quote, '"', '\''} {
+		if s, ok := quoteAs(value, q, multiline); ok {
+			return s, nil
+		}
+	}
+	return "", errors.New("value cannot be quoted")
+}
+
+func quoteAs(value string, quote byte, multiline bool) (string, bool) {
+	switch quote {
+	case 0:
+		if strings.TrimSpace(value) != value ||
+			strings.ContainsAny(value, "\r\n") ||
+			strings.Contains(value, " #") || strings.Contains(value, "\t#") ||
+			(len(value) > 0 && strings.ContainsRune("'\"`", rune(value[0]))) {
+			return "", false
+		}
+		return value, true
+	case '"':
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 0; i < len(value); i++ {
+			switch c := value[i]; c {
+			case '\\':
+				// \\ and \$ are kept for expansion, any other escape
+				// would change the value.
+				if i+1 == len(value) || strings.IndexByte("nrt\"\r\n", value[i+1]) >= 0 {
+					return "", false
+				}
+				b.WriteByte(c)
+				if value[i+1] == '\\' || value[i+1] == '$' {
+					i++
+					b.WriteByte(value[i])
+				}
+			case '"':
+				b.WriteString(`\"`)
+			case '\n':
+				if multiline {
+					b.WriteByte(c)
+				} else {
+					b.WriteString(`\n`)
+				}
+			case '\r':
+				b.WriteString(`\r`)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte('"')
+		return b.String(), true
+	default:
+		if strings.IndexByte(value, quote) >= 0 {
+			return "", false
+		}
+		return string(quote) + value + string(quote), true
+	}
+}
+
+// readHeredoc reads the lines of the heredoc that lp found in lines[*i], and
+// adds them to text. It leaves *i at the line with the delimiter.
+func readHeredoc(lp *LineParser, lines []string, i *int, text *string) (string, error) {
+	h := lp.heredoc
+	var body []string
+	for *i+1 < len(lines) && lines[*i+1] != "" {
+		*i++
+		*text += lines[*i]
+		line := strings.TrimRight(lines[*i], "\r\n")
+		if h.ends(line) {
+			return strings.Join(body, "\n"), nil
+		}
+		body = append(body, h.line(line))
+	}
+	return "", &ParseError{Err: fmt.Errorf("%w: %s", errUnterminatedHeredoc, h.delim), offset: lp.valueStart}
+}
+
+// heredocValue writes value as the heredoc h, keeping its delimiter. It
+// reports false if h is nil or the value holds a line that would end it.
+// Leading tabs are kept, since the heredoc is written without <<-.
+func heredocValue(value string, h *heredoc) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	plain := &heredoc{delim: h.delim}
+	for _, line := range strings.Split(value, "\n") {
+		if plain.ends(line) {
+			return "", false
+		}
+	}
+	delim := h.delim
+	if h.literal {
+		delim = "'" + delim + "'"
+	}
+	return "<<" + delim + "\n" + value + "\n" + h.delim, true
+}
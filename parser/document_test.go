@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDocument = `# Database settings
+export DB_HOST = localhost # the primary
+DB_PASSWORD='s3cr3t'
+
+DB_URL="postgres://${DB_HOST}"
+PRIVATE_KEY="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+DB_HOST=db.internal
+`
+
+func parseTestDocument(t *testing.T, content string) *Document {
+	t.Helper()
+	d, err := ParseDocument("test.env", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return d
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	testcases := []string{
+		testDocument,
+		"NO_TRAILING_NEWLINE=value",
+		"CRLF=value\r\n# comment\r\nQUOTED=\"a\\nb\" # comment\r\n",
+		"\xef\xbb\xbfBOM=value\n",
+		"\tTABS\t=\tvalue\t# comment\n",
+		"\n\n   \n# only comments\n",
+		"",
+	}
+
+	for _, tc := range testcases {
+		d := parseTestDocument(t, tc)
+		if got := string(d.Bytes()); got != tc {
+			t.Fatalf("want %q, got %q", tc, got)
+		}
+	}
+}
+
+func TestDocumentGet(t *testing.T) {
+	d := parseTestDocument(t, testDocument)
+
+	testcases := []struct {
+		key     string
+		want    string
+		wantSet bool
+	}{
+		{`DB_HOST`, `db.internal`, true},
+		{`DB_PASSWORD`, `s3cr3t`, true},
+		{`DB_URL`, `postgres://${DB_HOST}`, true},
+		{`PRIVATE_KEY`, "-----BEGIN KEY-----\nabc\n-----END KEY-----", true},
+		{`MISSING`, ``, false},
+	}
+
+	for _, tc := range testcases {
+		got, ok := d.Get(tc.key)
+		if ok != tc.wantSet || got != tc.want {
+			t.Fatalf("%s: want %q %t, got %q %t", tc.key, tc.want, tc.wantSet, got, ok)
+		}
+	}
+
+	if got, want := strings.Join(d.Keys(), ","), "DB_HOST,DB_PASSWORD,DB_URL,PRIVATE_KEY"; got != want {
+		t.Fatalf("keys: want %s, got %s", want, got)
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	d := parseTestDocument(t, testDocument)
+
+	sets := []struct{ key, value string }{
+		{`DB_PASSWORD`, `n3w`},
+		{`DB_URL`, `postgres://"quoted"`},
+		{`PRIVATE_KEY`, "-----BEGIN KEY-----\nxyz\n-----END KEY-----"},
+		{`DB_HOST`, `two words # not a comment`},
+		{`NEW_KEY`, `new`},
+		{`NEW_QUOTED`, ` padded`},
+	}
+	for _, s := range sets {
+		if err := d.Set(s.key, s.value); err != nil {
+			t.Fatalf("set %s: %v", s.key, err)
+		}
+	}
+
+	want := `# Database settings
+export DB_HOST = localhost # the primary
+DB_PASSWORD='n3w'
+
+DB_URL="postgres://\"quoted\""
+PRIVATE_KEY="-----BEGIN KEY-----
+xyz
+-----END KEY-----"
+DB_HOST="two words # not a comment"
+NEW_KEY=new
+NEW_QUOTED=" padded"
+`
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	reparsed := parseTestDocument(t, want)
+	for _, s := range sets {
+		if got, _ := reparsed.Get(s.key); got != s.value {
+			t.Fatalf("%s: want %q, got %q", s.key, s.value, got)
+		}
+	}
+}
+
+func TestDocumentSetAppendsNewline(t *testing.T) {
+	d := parseTestDocument(t, "KEY=value")
+	if err := d.Set("OTHER", "other"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := string(d.Bytes()), "KEY=value\nOTHER=other\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestDocumentSetBareKey(t *testing.T) {
+	d := parseTestDocument(t, "export HOST_DEP  # from the host\nOTHER=x\n")
+	if got, ok := d.Get("HOST_DEP"); !ok || got != "" {
+		t.Fatalf("want empty value, got %q, %v", got, ok)
+	}
+	if err := d.Set("HOST_DEP", "value"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := string(d.Bytes()), "export HOST_DEP=value  # from the host\nOTHER=x\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	d := parseTestDocument(t, testDocument)
+
+	if !d.Delete("DB_HOST") {
+		t.Fatalf("want DB_HOST deleted")
+	}
+	if d.Delete("DB_HOST") {
+		t.Fatalf("want DB_HOST already deleted")
+	}
+
+	want := `# Database settings
+DB_PASSWORD='s3cr3t'
+
+DB_URL="postgres://${DB_HOST}"
+PRIVATE_KEY="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+`
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestDocumentRename(t *testing.T) {
+	d := parseTestDocument(t, testDocument)
+
+	if err := d.Rename("DB_HOST", "DATABASE_HOST"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Rename("MISSING", "OTHER"); err == nil {
+		t.Fatalf("want error renaming a missing key")
+	}
+	if err := d.Rename("DB_URL", "DB_PASSWORD"); err == nil {
+		t.Fatalf("want error renaming to a declared key")
+	}
+
+	want := strings.NewReplacer(
+		"export DB_HOST =", "export DATABASE_HOST =",
+		"\nDB_HOST=", "\nDATABASE_HOST=",
+	).Replace(testDocument)
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestParseDocumentError(t *testing.T) {
+	_, err := ParseDocument("test.env", strings.NewReader("OK=value\nBROKEN=\"unterminated\n"))
+	if err == nil {
+		t.Fatalf("want error")
+	}
+	if !strings.Contains(err.Error(), "test.env:2:8") {
+		t.Fatalf("want error at test.env:2:8, got %v", err)
+	}
+}
+
+func TestParseDocumentKeyGrammar(t *testing.T) {
+	content := "app.name=genv\n"
+	if _, err := ParseDocument("test.env", strings.NewReader(content)); err == nil {
+		t.Fatalf("want error for a relaxed key")
+	}
+	d, err := ParseDocument("test.env", strings.NewReader(content), WithKeyGrammar(KeyGrammarRelaxed))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, _ := d.Get("app.name"); got != "genv" {
+		t.Fatalf("want %q, got %q", "genv", got)
+	}
+	if err := d.Set("app.version", "1"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Rename("app.name", "1-bad"); err == nil {
+		t.Fatalf("want error renaming to an invalid key")
+	}
+}
+
+func TestDocumentInvalidKeys(t *testing.T) {
+	d := parseTestDocument(t, testDocument)
+
+	if err := d.Set("bad key", "v"); err == nil {
+		t.Fatalf("want error setting an invalid key")
+	}
+	if err := d.Set("app.name", "v"); err == nil {
+		t.Fatalf("want error setting a relaxed key in a POSIX document")
+	}
+	if err := d.Rename("DB_URL", "1-bad"); err == nil {
+		t.Fatalf("want error renaming to an invalid key")
+	}
+
+	// The document is unchanged and still parses.
+	if got := string(d.Bytes()); got != testDocument {
+		t.Fatalf("want:\n%s\ngot:\n%s", testDocument, got)
+	}
+	parseTestDocument(t, string(d.Bytes()))
+}
+
+func TestDocumentSetCRLF(t *testing.T) {
+	d := parseTestDocument(t, "\xef\xbb\xbfKEY=\"first\r\nsecond\"\r\n")
+	if got, _ := d.Get("KEY"); got != "first\nsecond" {
+		t.Fatalf("want %q, got %q", "first\nsecond", got)
+	}
+	if err := d.Set("KEY", "third\nfourth"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Set("OTHER", "value"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := string(d.Bytes()), "\xef\xbb\xbfKEY=\"third\r\nfourth\"\r\nOTHER=value\r\n"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// stat returns the file info of f, if it has any.
+func stat(f io.Reader) (fs.FileInfo, error) {
+	if s, ok := f.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		return s.Stat()
+	}
+	return nil, fs.ErrInvalid
+}
+
+// parseDir declares a key for each file in the envdir directory src, with the
+// contents of the file as its value, trailing newlines trimmed. Hidden files,
+// such as the ..data links of Kubernetes volumes, subdirectories and files
+// whose names are not valid keys, such as application.yaml under the default
+// key grammar, are skipped. Files are read in the order of their names, and
+// their values are not expanded.
+func (p *Parser) parseDir(src source) error {
+	fsys, dir, join := src.fsys, src.name, path.Join
+	if fsys == nil {
+		fsys, dir, join = os.DirFS(src.name), ".", filepath.Join
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !p.keyGrammar.valid(name) {
+			continue
+		}
+		// Stat follows symlinks, which Kubernetes uses for every key.
+		info, err := fs.Stat(fsys, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		p.declareValue(name, strings.TrimRight(string(b), "\r\n"), join(src.name, name), 1, 1)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParserEnvdir(t *testing.T) {
+	// Lay the directory out like a Kubernetes volume, where every key is a
+	// link into a hidden directory.
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2024_01_01")
+	if err := os.Mkdir(data, 0o700); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	files := map[string]string{
+		"DB_HOST":     "localhost\n",
+		"DB_PASSWORD": "secret\r\n\n",
+		"CERT":        "line one\nline two\n",
+		"EMPTY":       "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(data, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := os.Symlink(data, filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	for name := range files {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o700); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	result, err := NewParser(dir).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	want := map[string]string{
+		"DB_HOST":     "localhost",
+		"DB_PASSWORD": "secret",
+		"CERT":        "line one\nline two",
+		"EMPTY":       "",
+	}
+	assertVars(t, result, want)
+	if e, _ := result.Lookup("DB_HOST"); e.File != filepath.Join(dir, "DB_HOST") {
+		t.Fatalf("want %s, got %s", filepath.Join(dir, "DB_HOST"), e.File)
+	}
+}
+
+func TestParserEnvdirFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"secrets/API_KEY":          {Data: []byte("key\n")},
+		"secrets/.hidden":          {Data: []byte("hidden\n")},
+		"secrets/log-level":        {Data: []byte("debug\n")},
+		"secrets/application.yaml": {Data: []byte("server:\n  port: 80\n")},
+	}
+
+	// Names that are not valid keys are skipped.
+	result, err := NewFSParser(fsys, "secrets").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertVars(t, result, map[string]string{"API_KEY": "key"})
+
+	result, err = NewFSParser(fsys, "secrets").With(WithKeyGrammar(KeyGrammarRelaxed)).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertVars(t, result, map[string]string{
+		"API_KEY":          "key",
+		"log-level":        "debug",
+		"application.yaml": "server:\n  port: 80",
+	})
+}
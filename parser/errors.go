@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ParseError describes a problem found in a parsed file.
+//
+// Use:
+//
+//	var perr *parser.ParseError
+//	if errors.As(err, &perr) {
+//	    log.Printf("%s:%d: %v", perr.File, perr.Line, perr.Err)
+//	}
+type ParseError struct {
+	File string
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+	// Text is the offending line. For errors found during expansion it is the
+	// raw value of the key being expanded.
+	Text string
+	// Err is the reason parsing failed.
+	Err error
+
+	// offset is the byte offset of the error in the text handed to the
+	// LineParser, until the error is located in the file.
+	offset int
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse: %s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// locate fills in the position of an error returned by the LineParser for
+// text, which starts at the given line of file. The LineParser was handed text
+// without its first indent bytes.
+func locate(err error, file string, line, indent int, text []byte) *ParseError {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		perr = &ParseError{Err: err}
+	}
+
+	before := text[:min(indent+perr.offset, len(text))]
+	n := bytes.Count(before, []byte("\n"))
+	perr.File = file
+	perr.Line = line + n
+	perr.Column = len(before) - bytes.LastIndexByte(before, '\n')
+	perr.Text = string(bytes.Split(text, []byte("\n"))[n])
+	return perr
+}
+
+// locateOffset returns an error located at the given byte offset of data,
+// the contents of file.
+func locateOffset(err error, file string, data []byte, at int) *ParseError {
+	line, column := lineColumn(data, at)
+	start := bytes.LastIndexByte(data[:min(at, len(data))], '\n') + 1
+	end := bytes.IndexByte(data[start:], '\n')
+	if end < 0 {
+		end = len(data) - start
+	}
+	text := bytes.TrimRight(data[start:start+end], "\r")
+	return &ParseError{File: file, Line: line, Column: column, Text: string(text), Err: err}
+}
+
+// lineColumn returns the 1-based line and column of the byte offset in data.
+func lineColumn(data []byte, at int) (line, column int) {
+	before := data[:min(at, len(data))]
+	return bytes.Count(before, []byte("\n")) + 1, len(before) - bytes.LastIndexByte(before, '\n')
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format is the file format of a parsed file.
+type Format int
+
+const (
+	// FormatAuto picks the format of each file by its extension, and reads
+	// files with any other extension as env files. This is the default.
+	FormatAuto Format = iota
+	// FormatEnv reads env files, see Dialect for their syntax.
+	FormatEnv
+	// FormatJSON reads a JSON object. Nested keys are joined with _ and
+	// upper-cased, so that {"db": {"host": "x"}} declares DB_HOST=x. Arrays
+	// of numbers, strings and booleans are joined with commas, so that
+	// genv.Get can split them back, and a string holding a comma is an error.
+	// Arrays holding objects or arrays declare a key per index, as in
+	// SERVERS_0_HOST. Null declares an empty value. Values are not expanded.
+	// Picked for the .json extension.
+	FormatJSON
+	// FormatProperties reads Java .properties files, with key=value,
+	// key: value and key value lines, comments starting with # or !, line
+	// continuations and \uXXXX escapes. Keys are upper-cased and dots and
+	// dashes are replaced with _ whatever the key grammar, so that db.host
+	// declares DB_HOST. Values are not expanded. Picked for the .properties
+	// extension.
+	FormatProperties
+	// FormatINI reads INI files, with key=value and key: value lines and
+	// comments starting with ; or #. Keys are prefixed with the name of their
+	// [section] and upper-cased, so that host in [db] declares DB_HOST. Values
+	// may be quoted, and are not expanded. Picked for the .ini extension.
+	FormatINI
+	// FormatSystemd reads files like the EnvironmentFile of a systemd unit,
+	// with systemd's quoting and line continuation rules. Values are not
+	// expanded. It is never picked by extension.
+	FormatSystemd
+)
+
+// WithFormat sets the format of the parsed files, including the files they
+// include. Directories are always read as envdir directories, see
+// NewParser.
+func WithFormat(format Format) Option {
+	return func(p *Parser) {
+		p.format = format
+	}
+}
+
+// formatOf returns the format to parse src in.
+func (p *Parser) formatOf(src source) Format {
+	if p.format != FormatAuto {
+		return p.format
+	}
+	switch strings.ToLower(path.Ext(src.name)) {
+	case ".json":
+		return FormatJSON
+	case ".properties":
+		return FormatProperties
+	case ".ini":
+		return FormatINI
+	default:
+		return FormatEnv
+	}
+}
+
+// envKey returns the env key for name nested under prefix, as in SECTION_KEY.
+// The name is upper-cased, and dots, dashes and chars that the key grammar
+// does not accept are replaced with _. A name without any char the grammar
+// accepts is invalid, rather than declaring a key made of underscores.
+func (p *Parser) envKey(prefix, name string) (string, error) {
+	var (
+		b     strings.Builder
+		valid bool
+	)
+	for _, r := range strings.ToUpper(name) {
+		keep := r != '.' && r != '-'
+		if r < utf8.RuneSelf {
+			keep = keep && p.keyGrammar.isKeyChar(byte(r))
+		} else {
+			// Only KeyGrammarAny accepts chars outside of ASCII.
+			keep = keep && r != utf8.RuneError && p.keyGrammar == KeyGrammarAny
+		}
+		if !keep {
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+		valid = true
+	}
+	key := b.String()
+	if !valid || (prefix == "" && !p.keyGrammar.isKeyStart(key[0])) {
+		return "", fmt.Errorf("invalid key: %q", name)
+	}
+	if prefix != "" {
+		return prefix + "_" + key, nil
+	}
+	return key, nil
+}
+
+// declareValue declares a value that is not expanded, as read from the
+// formats other than env files.
+func (p *Parser) declareValue(key, value, file string, line, column int) {
+	p.declare(Entry{
+		Key:     key,
+		Raw:     value,
+		Value:   value,
+		File:    file,
+		Line:    line,
+		Column:  column,
+		literal: true,
+	})
+}
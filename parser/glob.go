@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// matches returns the sources named by s, which may be a glob pattern, as in
+// config/*.env, and may end with ? to mark it as optional, as in .env.local?.
+// The trailing ? is never part of the pattern, so config/*.env? is an optional
+// glob.
+//
+// Matches are returned in sorted order. A pattern that matches no file fails
+// with fs.ErrNotExist, unless it is optional.
+func (s source) matches() ([]source, error) {
+	if s.r != nil {
+		return []source{s}, nil
+	}
+	if name, ok := strings.CutSuffix(s.name, "?"); ok {
+		s.name, s.optional = name, true
+	}
+	if !strings.ContainsAny(s.name, "*?[") {
+		return []source{s}, nil
+	}
+
+	var (
+		names []string
+		err   error
+	)
+	if s.fsys != nil {
+		names, err = fs.Glob(s.fsys, s.name)
+	} else {
+		names, err = filepath.Glob(s.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 && !s.optional {
+		return nil, fmt.Errorf("no file matches the pattern: %w", fs.ErrNotExist)
+	}
+	slices.Sort(names)
+
+	sources := make([]source, len(names))
+	for i, name := range names {
+		sources[i] = source{name: name, fsys: s.fsys, optional: s.optional}
+	}
+	return sources, nil
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParserGlob(t *testing.T) {
+	testcases := []struct {
+		name   string
+		parser *Parser
+	}{
+		{"files", NewParser("../testdata/glob/config/*.env", "../testdata/glob/.env.local?", "../testdata/glob/.env.missing?")},
+		{"fs", NewFSParser(os.DirFS("../testdata/glob"), "config/*.env", ".env.local?", ".env.missing?")},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.parser.Parse()
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if len(result.Vars) != 2 || result.Vars["NAME"] != "local" || result.Vars["PORT"] != "8080" {
+				t.Fatalf("want NAME=local PORT=8080, got %v", result.Vars)
+			}
+
+			// Matches are parsed in sorted order.
+			e, _ := result.Lookup("NAME")
+			var files []string
+			for _, o := range e.Overrides {
+				files = append(files, o.Value)
+			}
+			if len(files) != 2 || files[0] != "second" || files[1] != "base" {
+				t.Fatalf("want overrides second, base, got %v", files)
+			}
+		})
+	}
+}
+
+func TestParserGlobErrors(t *testing.T) {
+	testcases := []struct {
+		name    string
+		pattern string
+		wantErr error
+	}{
+		{"missing", "../testdata/glob/.env.missing", fs.ErrNotExist},
+		{"no match", "../testdata/glob/config/*.json", fs.ErrNotExist},
+		{"optional no match", "../testdata/glob/config/*.json?", nil},
+		{"bad pattern", "../testdata/glob/[", filepath.ErrBadPattern},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewParser(tc.pattern).Parse()
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// Optional files that exist still fail on errors in them.
+	name := writeEnv(t, "BROKEN LINE\n")
+	var perr *ParseError
+	if _, err := NewParser(name + "?").Parse(); !errors.As(err, &perr) {
+		t.Fatalf("want *ParseError, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+var errUnterminatedHeredoc = errors.New("unterminated heredoc")
+
+// heredoc describes a value given as a heredoc, such as
+//
+//	QUERY<<SQL
+//	SELECT * FROM users WHERE name = '$USER'
+//	SQL
+//
+// The lines between the header and the line holding only the delimiter are
+// the value, without the last newline. A quoted delimiter, as in <<'SQL',
+// turns off expansion in the value, and <<-SQL strips leading tabs from each
+// line, including the one with the delimiter.
+type heredoc struct {
+	delim   string
+	literal bool
+	strip   bool
+}
+
+// consumeHeredoc reads the header of a heredoc, from << to the end of the
+// line.
+func (p *LineParser) consumeHeredoc() error {
+	p.valueStart = p.position
+	p.consume()
+	p.consume()
+
+	h := &heredoc{}
+	if p.curr == '-' {
+		h.strip = true
+		p.consume()
+	}
+	if q := p.curr; q == '\'' || q == '"' {
+		p.consume()
+		end := bytes.IndexByte(p.line[p.position:], q)
+		if end < 0 {
+			return p.errorf("unterminated heredoc delimiter")
+		}
+		h.delim, h.literal = string(p.line[p.position:p.position+end]), true
+		for range end + 1 {
+			p.consume()
+		}
+	} else {
+		start := p.position
+		for !p.eol() && p.curr != ' ' && p.curr != '\t' {
+			p.consume()
+		}
+		h.delim = string(p.line[start:p.position])
+	}
+	if h.delim == "" {
+		return p.errorf("expected heredoc delimiter")
+	}
+	p.valueEnd = p.position
+
+	p.skipWhitespace()
+	if !p.eol() {
+		return p.errorf("unexpected char: %s, expected end of line", string(p.curr))
+	}
+	p.heredoc = h
+	return nil
+}
+
+// line returns a line of the heredoc as it is added to the value.
+func (h *heredoc) line(text string) string {
+	if h.strip {
+		return strings.TrimLeft(text, "\t")
+	}
+	return text
+}
+
+// ends reports whether the line ends the heredoc. Trailing whitespace after
+// the delimiter is ignored.
+func (h *heredoc) ends(text string) bool {
+	return strings.TrimRight(h.line(text), " \t") == h.delim
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParserHeredoc(t *testing.T) {
+	result, err := NewParser("../testdata/.env.heredoc").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := map[string]string{
+		"TABLE":    "users",
+		"QUERY":    "SELECT *\n  FROM users\n  WHERE name = '$1' # not a comment",
+		"CONFIG":   "{\n  \"key\": \"${NOT_EXPANDED}\",\n\n  \"quote\": \"it's\"\n}",
+		"INDENTED": "first\nsecond",
+		"EMPTY":    "",
+		"AFTER":    "users",
+	}
+	assertVars(t, result, want)
+
+	if e, _ := result.Lookup("AFTER"); e.Line != 20 {
+		t.Fatalf("want line 20, got %d", e.Line)
+	}
+}
+
+func TestParserHeredocErrors(t *testing.T) {
+	testcases := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"A=1\n  KEY<<EOF\nbody\nEOF not the end\n", 2, 6},
+		{"KEY<<'EOF\nbody\nEOF\n", 1, 7},
+		{"KEY<<\nbody\n", 1, 6},
+		{"KEY<<EOF trailing\nEOF\n", 1, 10},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewParser(writeEnv(t, tc.input)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: want *ParseError, got %v", tc.input, err)
+		}
+		if perr.Line != tc.line || perr.Column != tc.column {
+			t.Fatalf("%q: want %d:%d, got %d:%d: %v", tc.input, tc.line, tc.column, perr.Line, perr.Column, err)
+		}
+	}
+
+	_, err := NewParser(writeEnv(t, "KEY<<EOF\nbody\n")).Parse()
+	if !errors.Is(err, errUnterminatedHeredoc) || !strings.Contains(err.Error(), "EOF") {
+		t.Fatalf("want %v with the delimiter, got %v", errUnterminatedHeredoc, err)
+	}
+}
+
+func TestDocumentHeredoc(t *testing.T) {
+	content := "BEFORE=1\nQUERY<<'SQL'  \nSELECT 1\nSQL\nAFTER=2\n"
+	d := parseTestDocument(t, content)
+	if got := string(d.Bytes()); got != content {
+		t.Fatalf("want %q, got %q", content, got)
+	}
+	if got, _ := d.Get("QUERY"); got != "SELECT 1" {
+		t.Fatalf("want %q, got %q", "SELECT 1", got)
+	}
+
+	if err := d.Set("QUERY", "SELECT 2\nFROM t"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	want := "BEFORE=1\nQUERY<<'SQL'\nSELECT 2\nFROM t\nSQL\nAFTER=2\n"
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	// A value holding the delimiter cannot be written as the heredoc.
+	if err := d.Set("QUERY", "a\nSQL"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	want = "BEFORE=1\nQUERY=\"a\nSQL\"\nAFTER=2\n"
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
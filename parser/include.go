@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrIncludeCycle is returned by Parser.Parse when a file includes itself,
+// directly or through other files.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// includeDirective returns the path of an include directive, a comment such
+// as
+//
+//	# @include ../shared.env
+//
+// The directive @include? marks the include as optional.
+func includeDirective(line []byte) (path string, optional, ok bool) {
+	if len(line) == 0 || line[0] != '#' {
+		return "", false, false
+	}
+	rest := bytes.TrimLeft(line[1:], " \t")
+	if !bytes.HasPrefix(rest, []byte("@include")) {
+		return "", false, false
+	}
+	rest = rest[len("@include"):]
+	if len(rest) > 0 && rest[0] == '?' {
+		optional = true
+		rest = rest[1:]
+	}
+	if len(rest) == 0 || (rest[0] != ' ' && rest[0] != '\t') {
+		return "", false, false
+	}
+	path = strings.Trim(string(bytes.TrimSpace(rest)), `"'`)
+	return path, optional, path != ""
+}
+
+// include parses the file at name, relative to the directory of the source
+// that includes it. Its keys are declared in place of the directive, so that
+// they override the keys declared above it and are overridden by the keys
+// declared below it, as with the filenames passed to NewParser.
+func (p *Parser) include(from source, name string, optional bool) error {
+	src := source{name: name, fsys: from.fsys}
+	switch {
+	case from.fsys != nil:
+		if !path.IsAbs(name) {
+			src.name = path.Join(path.Dir(from.name), name)
+		}
+	case !filepath.IsAbs(name):
+		src.name = filepath.Join(filepath.Dir(from.name), name)
+	}
+
+	if i := slices.Index(p.including, src.id()); i >= 0 {
+		chain := append(slices.Clone(p.including[i:]), src.id())
+		return fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+	}
+
+	// Errors from within the included file are already located, any other
+	// error comes from opening or reading it.
+	err := p.parseSource(src)
+	var perr *ParseError
+	switch {
+	case err == nil || errors.As(err, &perr):
+		return err
+	case optional && errors.Is(err, fs.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("include %s: %w", name, err)
+	}
+}
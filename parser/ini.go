@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// parseINI declares the keys of the INI file read from r. See FormatINI.
+func (p *Parser) parseINI(src source, r io.Reader) error {
+	var (
+		scanner = newLineReader(r)
+		lineNum int
+		// prefix is the env key of the current section.
+		prefix string
+	)
+	for scanner.Scan() {
+		lineNum++
+		text := string(scanner.Bytes())
+		line := strings.TrimSpace(text)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+
+		var err error
+		if line[0] == '[' {
+			prefix, err = p.iniSection(line)
+		} else {
+			var key, value string
+			if key, value, err = p.iniValue(prefix, line); err == nil {
+				p.declareValue(key, value, src.name, lineNum, indent+1)
+			}
+		}
+		if err != nil {
+			perr := &ParseError{File: src.name, Line: lineNum, Column: indent + 1, Text: text, Err: err}
+			if !p.report(perr) {
+				return perr
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// iniSection returns the key prefix for a section header, as in [section].
+func (p *Parser) iniSection(line string) (string, error) {
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		return "", errors.New("unterminated section header")
+	}
+	if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+		return "", errors.New("unexpected text after section header")
+	}
+	name := strings.TrimSpace(line[1:end])
+	if name == "" {
+		return "", errors.New("empty section name")
+	}
+	return p.envKey("", name)
+}
+
+// iniValue returns the key and value of a key=value or key: value line. A
+// value wrapped in matching quotes is unquoted.
+func (p *Parser) iniValue(prefix, line string) (key, value string, err error) {
+	i := strings.IndexAny(line, "=:")
+	if i < 0 {
+		return "", "", errors.New("expected = or : after key")
+	}
+	name := strings.TrimSpace(line[:i])
+	if name == "" {
+		return "", "", errors.New("missing key")
+	}
+	value = strings.TrimSpace(line[i+1:])
+	if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+		value = value[1 : n-1]
+	}
+	if key, err = p.envKey(prefix, name); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
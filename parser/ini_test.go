@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParserINI(t *testing.T) {
+	result, err := NewParser("../testdata/formats/app.ini").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := map[string]string{
+		"NAME":                 "service",
+		"DB_HOST":              "localhost",
+		"DB_PORT":              "5432",
+		"DB_PASSWORD":          "p;ss # word",
+		"FEATURE_FLAGS_NEW_UI": "true",
+	}
+	assertVars(t, result, want)
+}
+
+func TestParserINIErrors(t *testing.T) {
+	testcases := []struct {
+		input string
+		line  int
+	}{
+		{"[db\nhost=x\n", 1},
+		{"[db] extra\n", 1},
+		{"[]\n", 1},
+		{"[db]\nhost\n", 2},
+		{"= value\n", 1},
+		{"1key = value\n", 1},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewBytesParser("app.ini", []byte(tc.input)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: want *ParseError, got %v", tc.input, err)
+		}
+		if perr.Line != tc.line {
+			t.Fatalf("%q: want line %d, got %d: %v", tc.input, tc.line, perr.Line, err)
+		}
+	}
+}
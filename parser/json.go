@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	errUnexpectedEOF = errors.New("unexpected end of JSON input")
+	errArrayComma    = errors.New("array value holds a comma")
+)
+
+// parseJSON declares the keys of the JSON object read from r. See FormatJSON.
+func (p *Parser) parseJSON(src source, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	w := &jsonWalker{p: p, src: src, data: data, at: -1}
+	w.dec = json.NewDecoder(bytes.NewReader(data))
+	w.dec.UseNumber()
+
+	at := w.offset()
+	tok, err := w.dec.Token()
+	if err != nil {
+		return w.errorf(err)
+	}
+	if tok != json.Delim('{') {
+		return w.errorAt(errors.New("expected a JSON object"), at)
+	}
+	if err := w.object(""); err != nil {
+		return err
+	}
+	at = w.offset()
+	switch _, err := w.dec.Token(); {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return w.errorf(err)
+	default:
+		return w.errorAt(errors.New("unexpected data after the JSON object"), at)
+	}
+}
+
+// jsonWalker declares the values of a JSON document as it reads its tokens.
+type jsonWalker struct {
+	p   *Parser
+	src source
+	// data is the whole file, which positions refer to.
+	data []byte
+	dec  *json.Decoder
+	// at is the offset in data of every value declared by the walker, or -1
+	// to use the offset of the key of each value.
+	at int
+}
+
+// object declares the members of an object whose opening brace was read.
+func (w *jsonWalker) object(prefix string) error {
+	for w.dec.More() {
+		at := w.offset()
+		tok, err := w.dec.Token()
+		if err != nil {
+			return w.errorf(err)
+		}
+		key, err := w.key(prefix, tok.(string), at)
+		if err != nil {
+			return err
+		}
+		if err := w.value(key, at); err != nil {
+			return err
+		}
+	}
+	// Read the closing brace.
+	if _, err := w.dec.Token(); err != nil {
+		return w.errorf(err)
+	}
+	return nil
+}
+
+// value declares the next value in the input as key.
+func (w *jsonWalker) value(key string, at int) error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return w.errorf(err)
+	}
+	switch tok {
+	case json.Delim('{'):
+		return w.object(key)
+	case json.Delim('['):
+		return w.array(key, at)
+	}
+	w.declare(key, scalar(tok), at)
+	return nil
+}
+
+// array declares the elements of an array whose opening bracket was read.
+// The elements are read whole, to find out whether the array holds objects or
+// arrays, which declare a key per index, or plain values, which are joined.
+func (w *jsonWalker) array(key string, at int) error {
+	var (
+		elems   []json.RawMessage
+		offsets []int
+		nested  bool
+	)
+	for w.dec.More() {
+		offsets = append(offsets, w.offset())
+		var raw json.RawMessage
+		if err := w.dec.Decode(&raw); err != nil {
+			return w.errorf(err)
+		}
+		nested = nested || raw[0] == '{' || raw[0] == '['
+		elems = append(elems, raw)
+	}
+	// Read the closing bracket.
+	if _, err := w.dec.Token(); err != nil {
+		return w.errorf(err)
+	}
+
+	if !nested {
+		values := make([]string, len(elems))
+		for i, raw := range elems {
+			var v any
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := dec.Decode(&v); err != nil {
+				return w.errorf(err)
+			}
+			values[i] = scalar(v)
+			// Joined values must split back into the elements.
+			if strings.Contains(values[i], ",") {
+				return w.errorAt(errArrayComma, offsets[i])
+			}
+		}
+		w.declare(key, strings.Join(values, ","), at)
+		return nil
+	}
+
+	// Values inside the array are located at its key.
+	if w.at >= 0 {
+		at = w.at
+	}
+	for i, raw := range elems {
+		elem := &jsonWalker{p: w.p, src: w.src, data: w.data, at: at}
+		elem.dec = json.NewDecoder(bytes.NewReader(raw))
+		elem.dec.UseNumber()
+		if err := elem.value(key+"_"+strconv.Itoa(i), at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key returns the env key for the member name of an object nested under
+// prefix.
+func (w *jsonWalker) key(prefix, name string, at int) (string, error) {
+	key, err := w.p.envKey(prefix, name)
+	if err != nil {
+		return "", w.errorAt(err, at)
+	}
+	return key, nil
+}
+
+func (w *jsonWalker) declare(key, value string, at int) {
+	if w.at >= 0 {
+		at = w.at
+	}
+	line, column := lineColumn(w.data, at)
+	w.p.declareValue(key, value, w.src.name, line, column)
+}
+
+// scalar returns the text of a JSON string, number, boolean or null.
+func scalar(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// offset returns the offset of the next token in data.
+func (w *jsonWalker) offset() int {
+	if w.at >= 0 {
+		return w.at
+	}
+	at := int(w.dec.InputOffset())
+	for at < len(w.data) && strings.IndexByte(" \t\r\n,", w.data[at]) >= 0 {
+		at++
+	}
+	return at
+}
+
+// errorf locates an error returned by the decoder.
+func (w *jsonWalker) errorf(err error) error {
+	at := w.offset()
+	var serr *json.SyntaxError
+	switch {
+	case w.at >= 0:
+		// Offsets of the decoder are relative to an array element.
+	case errors.As(err, &serr):
+		// The offset counts the char the error was found at, or the whole
+		// input when it ended too early.
+		at = max(int(serr.Offset)-1, 0)
+		if int(serr.Offset) >= len(w.data) {
+			at = len(w.data)
+		}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		at, err = len(w.data), errUnexpectedEOF
+	}
+	return w.errorAt(err, at)
+}
+
+func (w *jsonWalker) errorAt(err error, at int) error {
+	return locateOffset(err, w.src.name, w.data, at)
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParserJSON(t *testing.T) {
+	result, err := NewParser("../testdata/json/config.json").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := map[string]string{
+		"NAME":           "service",
+		"PORT":           "8080",
+		"RATIO":          "0.75",
+		"DEBUG":          "true",
+		"TOKEN":          "",
+		"DB_HOST":        "localhost",
+		"DB_MAX_CONNS":   "10",
+		"TAGS":           "a,b,c",
+		"PORTS":          "80,443",
+		"SERVERS_0_HOST": "one",
+		"SERVERS_1_HOST": "two",
+	}
+	assertVars(t, result, want)
+
+	var keys []string
+	for _, e := range result.Entries {
+		keys = append(keys, e.Key)
+	}
+	if got, want := strings.Join(keys, " "), "NAME PORT RATIO DEBUG TOKEN DB_HOST DB_MAX_CONNS TAGS PORTS SERVERS_0_HOST SERVERS_1_HOST"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+
+	e, _ := result.Lookup("DB_HOST")
+	if e.Line != 8 || e.Column != 5 {
+		t.Fatalf("want 8:5, got %d:%d", e.Line, e.Column)
+	}
+	e, _ = result.Lookup("SERVERS_1_HOST")
+	if e.Line != 13 || e.Column != 3 {
+		t.Fatalf("want 13:3, got %d:%d", e.Line, e.Column)
+	}
+}
+
+func TestParserJSONOverride(t *testing.T) {
+	result, err := NewParser("../testdata/json/override.env").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := result.Vars["URL"], "http://localhost:9090"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	e, _ := result.Lookup("PORT")
+	if len(e.Overrides) != 1 || e.Overrides[0].Value != "8080" {
+		t.Fatalf("want PORT to override 8080, got %+v", e.Overrides)
+	}
+}
+
+func TestParserJSONFormat(t *testing.T) {
+	content := `{"key": "${NOT_EXPANDED}"}`
+	result, err := NewReaderParser("<reader>", strings.NewReader(content)).With(WithFormat(FormatJSON)).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := result.Vars["KEY"], "${NOT_EXPANDED}"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestParserJSONKeys(t *testing.T) {
+	content := []byte(`{"café": 1, "db": {"ключ": 2}}`)
+	result, err := NewBytesParser("config.json", content).With(WithKeyGrammar(KeyGrammarAny)).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertVars(t, result, map[string]string{"CAFÉ": "1", "DB_КЛЮЧ": "2"})
+
+	content = []byte(`{"café": 1}`)
+	result, err = NewBytesParser("config.json", content).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertVars(t, result, map[string]string{"CAF_": "1"})
+}
+
+func TestParserJSONErrors(t *testing.T) {
+	testcases := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"[1, 2]", 1, 1},
+		{"{\n  \"a\": 1,\n  \"b\": }\n", 3, 8},
+		{"{\n  \"a\": 1\n", 3, 1},
+		{"{\"1a\": 1}", 1, 2},
+		{"{} {}", 1, 4},
+		{`{"tags": ["a", "b,c"]}`, 1, 16},
+		{`{"db": {"ключ": 1}}`, 1, 9},
+		{"{\n  \"servers\": [{\"tags\": [\"a,b\"]}]\n}", 2, 3},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewBytesParser("config.json", []byte(tc.input)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: want *ParseError, got %v", tc.input, err)
+		}
+		if perr.Line != tc.line || perr.Column != tc.column {
+			t.Fatalf("%q: want %d:%d, got %d:%d: %v", tc.input, tc.line, tc.column, perr.Line, perr.Column, err)
+		}
+	}
+}
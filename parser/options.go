@@ -0,0 +1,116 @@
+package parser
+
+// An Option configures a Parser.
+type Option func(*Parser)
+
+// UndefinedPolicy decides what happens to a reference to a key that is
+// neither declared in the parsed files nor found by the lookup function.
+type UndefinedPolicy int
+
+const (
+	// UndefinedError makes Parser.Parse fail. This is the default.
+	UndefinedError UndefinedPolicy = iota
+	// UndefinedEmpty replaces the reference with an empty string.
+	UndefinedEmpty
+	// UndefinedKeep keeps the reference as it was written, as in ${NAME}.
+	UndefinedKeep
+)
+
+// KeyGrammar decides which keys are valid.
+type KeyGrammar int
+
+const (
+	// KeyGrammarPOSIX accepts the keys a POSIX shell accepts as variable
+	// names, [A-Za-z_][A-Za-z0-9_]*. This is the default.
+	KeyGrammarPOSIX KeyGrammar = iota
+	// KeyGrammarRelaxed also accepts dots and dashes after the first char,
+	// as in app.name or my-key.
+	KeyGrammarRelaxed
+	// KeyGrammarAny accepts any char but whitespace and =, like
+	// docker run --env-file.
+	KeyGrammarAny
+)
+
+func (g KeyGrammar) isKeyStart(c byte) bool {
+	if g == KeyGrammarAny {
+		return c != '=' && c != ' ' && c != '\t' && c != '\n' && c != '\r'
+	}
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func (g KeyGrammar) isKeyChar(c byte) bool {
+	if g == KeyGrammarRelaxed && (c == '.' || c == '-') {
+		return true
+	}
+	return g.isKeyStart(c) || ('0' <= c && c <= '9')
+}
+
+// valid reports whether key is a valid key in the grammar.
+func (g KeyGrammar) valid(key string) bool {
+	if key == "" || !g.isKeyStart(key[0]) {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		if !g.isKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithLookup sets the function used to resolve references to keys that are
+// not declared in the parsed files. It defaults to os.LookupEnv, a nil lookup
+// only resolves declared keys.
+func WithLookup(lookup func(key string) (string, bool)) Option {
+	return func(p *Parser) {
+		p.lookup = lookup
+	}
+}
+
+// WithUndefined sets the policy for references to undefined keys.
+func WithUndefined(policy UndefinedPolicy) Option {
+	return func(p *Parser) {
+		p.undefined = policy
+	}
+}
+
+// WithKeyGrammar sets the grammar keys must follow.
+func WithKeyGrammar(grammar KeyGrammar) Option {
+	return func(p *Parser) {
+		p.keyGrammar = grammar
+	}
+}
+
+// WithEnvPrecedence makes the values found by the lookup function, by default
+// the process environment, win over the values declared in the parsed files,
+// both in the result and in the references to them. It is how a variable set
+// when starting the program, as in FOO=x ./app, overrides the files.
+func WithEnvPrecedence() Option {
+	return func(p *Parser) {
+		p.envPrecedence = true
+	}
+}
+
+// WithAllErrors makes Parser.Parse keep going after a problem and report
+// every problem it finds, joined with errors.Join. Lines that cannot be parsed
+// are skipped.
+func WithAllErrors() Option {
+	return func(p *Parser) {
+		p.allErrors = true
+	}
+}
+
+// With applies opts to the parser and returns it.
+//
+// Use:
+//
+//	p := parser.NewParser(".env").With(
+//	    parser.WithLookup(nil),
+//	    parser.WithUndefined(parser.UndefinedKeep),
+//	)
+func (p *Parser) With(opts ...Option) *Parser {
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
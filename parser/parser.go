@@ -1,97 +1,703 @@
 package parser
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"regexp"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
+	"unicode"
 )
 
+// ErrReferenceCycle is returned by Parser.Parse when a value refers back to
+// itself through other keys. A value referring to its own key directly, as in
+// PORT=${PORT:-8080}, refers to the value it overrides instead.
+var ErrReferenceCycle = errors.New("reference cycle")
+
+var errUnterminatedQuote = errors.New("unterminated quoted value")
+
 type ParseResult struct {
-	mu   sync.Mutex
 	Vars map[string]string
+	// Entries holds one entry per key, in the order keys were first declared.
+	Entries []Entry
+	index   map[string]int
+}
+
+// Lookup returns the entry for key.
+func (r *ParseResult) Lookup(key string) (Entry, bool) {
+	i, ok := r.index[key]
+	if !ok {
+		return Entry{}, false
+	}
+	return r.Entries[i], true
+}
+
+// Entry is the effective declaration of a key.
+type Entry struct {
+	Key string
+	// Raw is the value as declared, unquoted but not expanded.
+	Raw string
+	// Value is the expanded value.
+	Value string
+	// File, Line and Column locate the declaration. Line and Column are
+	// 1-based, and are 0 for keys assigned during expansion, as in ${KEY:=word}.
+	File   string
+	Line   int
+	Column int
+	// Overrides holds the earlier declarations of the key that this entry
+	// replaced, oldest first. Their values are never expanded.
+	Overrides []Entry
+	// literal values are never expanded.
+	literal bool
+}
+
+// Position returns the location of the declaration as file:line.
+func (e Entry) Position() string {
+	return fmt.Sprintf("%s:%d", e.File, e.Line)
 }
 
 type Parser struct {
-	filenames []string
+	sources    []source
+	lookup     func(key string) (string, bool)
+	undefined  UndefinedPolicy
+	dialect    Dialect
+	keyGrammar KeyGrammar
+	format     Format
+	commands   *commandConfig
+	// requireBare makes bare keys missing from the environment an error.
+	requireBare   bool
+	envPrecedence bool
+	allErrors     bool
+	errs          []error
+	// failed holds the error of every key that failed to expand.
+	failed map[string]error
+	// including holds the chain of sources currently being parsed.
+	including []string
 	result    *ParseResult
+	expanded  map[string]bool
+	// expanding holds the chain of keys currently being expanded, and chain
+	// holds the index of each key in it.
+	expanding []string
+	chain     map[string]int
+	// shadowed holds, for keys referencing their own name, how many of their
+	// declarations are skipped to find the value they refer to.
+	shadowed map[string]int
+}
+
+// source is a named input of the parser.
+type source struct {
+	name string
+	// fsys is nil for files on disk.
+	fsys fs.FS
+	// r is set for inputs that are not opened by name.
+	r io.Reader
+	// optional sources are skipped when they do not exist.
+	optional bool
+}
+
+func (s source) open() (io.ReadCloser, error) {
+	switch {
+	case s.r != nil:
+		return io.NopCloser(s.r), nil
+	case s.fsys != nil:
+		return s.fsys.Open(s.name)
+	default:
+		return os.Open(s.name)
+	}
+}
+
+// id identifies the source in include chains.
+func (s source) id() string {
+	if s.fsys != nil || s.r != nil {
+		return path.Clean(s.name)
+	}
+	if abs, err := filepath.Abs(s.name); err == nil {
+		return abs
+	}
+	return filepath.Clean(s.name)
 }
 
+// NewParser returns a parser for the named files on disk.
+//
+// A name may be a glob pattern, as in config/*.env, which is expanded in
+// sorted order when parsing. A name ending with ? is optional, as in
+// .env.local?, and is skipped if it does not exist. A pattern that matches no
+// file fails, unless it is optional.
+//
+// A directory is read as an envdir directory, as mounted for Kubernetes
+// ConfigMaps and Secrets: each file declares a key named after it, with the
+// contents of the file as its value. See FormatAuto for the formats of files.
 func NewParser(filenames ...string) *Parser {
+	sources := make([]source, len(filenames))
+	for i, filename := range filenames {
+		sources[i] = source{name: filename}
+	}
+	return newParser(sources...)
+}
+
+// NewReaderParser returns a parser that reads from r. The name is used in
+// errors and entries in place of a filename.
+func NewReaderParser(name string, r io.Reader) *Parser {
+	return newParser(source{name: name, r: r})
+}
+
+// NewBytesParser returns a parser for the contents of b. The name is used in
+// errors and entries in place of a filename.
+func NewBytesParser(name string, b []byte) *Parser {
+	return NewReaderParser(name, bytes.NewReader(b))
+}
+
+// NewFSParser returns a parser for the named files in fsys, such as an
+// embed.FS. Paths may be glob patterns and marked optional, see NewParser.
+func NewFSParser(fsys fs.FS, paths ...string) *Parser {
+	sources := make([]source, len(paths))
+	for i, path := range paths {
+		sources[i] = source{name: path, fsys: fsys}
+	}
+	return newParser(sources...)
+}
+
+func newParser(sources ...source) *Parser {
 	return &Parser{
-		filenames: filenames,
+		sources: sources,
+		lookup:  os.LookupEnv,
+		dialect: DialectGenv,
 		result: &ParseResult{
-			Vars: make(map[string]string),
+			Vars:  make(map[string]string),
+			index: make(map[string]int),
 		},
+		expanded: make(map[string]bool),
+		failed:   make(map[string]error),
+		chain:    make(map[string]int),
+		shadowed: make(map[string]int),
 	}
 }
 
+// Parse parses the files in order and expands the values.
+//
+// Problems in the files are reported as a *ParseError. See WithAllErrors to
+// report every problem instead of only the first one.
 func (p *Parser) Parse() (result *ParseResult, err error) {
-	for _, file := range p.filenames {
-		if err := p.parseFile(file); err != nil {
-			return result, fmt.Errorf("parse: %s: %w", file, err)
+	for _, pattern := range p.sources {
+		sources, err := pattern.matches()
+		if err != nil {
+			return result, fmt.Errorf("parse: %s: %w", pattern.name, err)
+		}
+		for _, src := range sources {
+			if err := p.parseSource(src); err != nil {
+				var perr *ParseError
+				if errors.As(err, &perr) {
+					return result, err
+				}
+				if src.optional && errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return result, fmt.Errorf("parse: %s: %w", src.name, err)
+			}
 		}
 	}
 
-	pattern := regexp.MustCompile(`\$\{([a-zA-Z0-9_]*)\}`)
-	for k, v := range p.result.Vars {
-		for {
-			match := pattern.FindStringSubmatch(v)
-			if len(match) != 2 {
-				break
+	if p.envPrecedence && p.lookup != nil {
+		for i := range p.result.Entries {
+			e := &p.result.Entries[i]
+			if v, ok := p.lookup(e.Key); ok {
+				e.Value = v
+				p.result.Vars[e.Key] = v
+				p.expanded[e.Key] = true
 			}
-			ev, ok := p.result.Vars[match[1]]
-			if !ok {
-				return nil, fmt.Errorf("genv: environment variable not set: %q", match[1])
+		}
+	}
+
+	for i := range p.result.Entries {
+		if _, err := p.expand(p.result.Entries[i].Key); err != nil {
+			if !p.report(err) {
+				return nil, err
 			}
-			v = strings.ReplaceAll(v, match[0], ev)
-			p.result.mu.Lock()
-			p.result.Vars[k] = v
-			p.result.mu.Unlock()
 		}
 	}
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
 	return p.result, err
 }
 
-func (p *Parser) parseFile(filename string) error {
-	f, err := os.Open(filename)
+// report records err when all errors are collected, and reports whether
+// parsing can go on.
+func (p *Parser) report(err error) bool {
+	if !p.allErrors {
+		return false
+	}
+	if !slices.Contains(p.errs, err) {
+		p.errs = append(p.errs, err)
+	}
+	return true
+}
+
+func (p *Parser) parseSource(src source) error {
+	f, err := src.open()
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	p.including = append(p.including, src.id())
+	defer func() { p.including = p.including[:len(p.including)-1] }()
+
+	if info, err := stat(f); err == nil && info.IsDir() {
+		return p.parseDir(src)
+	}
+	switch p.formatOf(src) {
+	case FormatJSON:
+		return p.parseJSON(src, f)
+	case FormatProperties:
+		return p.parseProperties(src, f)
+	case FormatINI:
+		return p.parseINI(src, f)
+	case FormatSystemd:
+		return p.parseSystemd(src, f)
+	default:
+		return p.parseEnv(src, f)
+	}
+}
+
+// parseEnv declares the keys of the env file read from r.
+func (p *Parser) parseEnv(src source, r io.Reader) error {
+	var (
+		scanner = newLineReader(r)
+		lineNum int
+	)
 	for scanner.Scan() {
-		line := scanner.Bytes()
-		line = bytes.TrimSpace(line)
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if path, optional, ok := includeDirective(line); ok {
+			if err := p.include(src, path, optional); err != nil {
+				var perr *ParseError
+				if !errors.As(err, &perr) {
+					indent := len(scanner.Bytes()) - len(bytes.TrimLeftFunc(scanner.Bytes(), unicode.IsSpace))
+					perr = locate(err, src.name, lineNum, indent, scanner.Bytes())
+				}
+				if !p.report(perr) {
+					return perr
+				}
+			}
+			continue
+		}
 		if (len(line) > 0 && line[0] == '#') || len(line) == 0 {
 			continue
 		}
-		lp := NewLineParser(line)
+
+		// A quoted value may span several lines, so keep reading until the
+		// closing quote is found. Errors point at the line where it started.
+		start := lineNum
+		logical := append([]byte(nil), scanner.Bytes()...)
+		indent := len(logical) - len(bytes.TrimLeftFunc(logical, unicode.IsSpace))
+		lp := p.newLineParser(logical[indent:])
 		key, value, err := lp.parse()
+		for errors.Is(err, errUnterminatedQuote) && scanner.Scan() {
+			lineNum++
+			logical = append(append(logical, '\n'), scanner.Bytes()...)
+			lp = p.newLineParser(logical[indent:])
+			key, value, err = lp.parse()
+		}
 		if err != nil {
-			return err
+			perr := locate(err, src.name, start, indent, logical)
+			if !p.report(perr) {
+				return perr
+			}
+			continue
+		}
+		if key == "" {
+			continue
 		}
-		if key == "" || value == "" {
+		if h := lp.heredoc; h != nil {
+			var lines []string
+			terminated := false
+			for !terminated && scanner.Scan() {
+				lineNum++
+				text := string(scanner.Bytes())
+				if terminated = h.ends(text); !terminated {
+					lines = append(lines, h.line(text))
+				}
+			}
+			if !terminated {
+				err := &ParseError{Err: fmt.Errorf("%w: %s", errUnterminatedHeredoc, h.delim), offset: lp.valueStart}
+				perr := locate(err, src.name, start, indent, logical)
+				if !p.report(perr) {
+					return perr
+				}
+				continue
+			}
+			value = strings.Join(lines, "\n")
+		}
+		if lp.bare {
+			if err := p.declareBare(key, src.name, start, indent+lp.keyStart+1); err != nil {
+				perr := locate(&ParseError{Err: err, offset: lp.keyStart}, src.name, start, indent, logical)
+				if !p.report(perr) {
+					return perr
+				}
+			}
 			continue
 		}
-		p.result.mu.Lock()
-		p.result.Vars[key] = value
-		p.result.mu.Unlock()
+		p.declare(Entry{
+			Key:    key,
+			Raw:    value,
+			Value:  value,
+			File:   src.name,
+			Line:   start,
+			Column: indent + lp.keyStart + 1,
+			// Single-quoted and backtick values are literal and never expanded.
+			literal: lp.quote == '\'' || lp.quote == '`' || (lp.heredoc != nil && lp.heredoc.literal) || !p.dialect.Expand,
+		})
 	}
+	return scanner.Err()
+}
 
-	if err := f.Close(); err != nil {
-		return err
+// expand expands the value of key and stores the result, so that each key is
+// only expanded once.
+func (p *Parser) expand(key string) (string, error) {
+	value := p.result.Vars[key]
+	if p.expanded[key] {
+		return value, nil
+	}
+	if err, ok := p.failed[key]; ok {
+		return "", err
+	}
+
+	if i, ok := p.chain[key]; ok {
+		chain := append(slices.Clone(p.expanding[i:]), key)
+		return "", fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(chain, " -> "))
+	}
+	p.chain[key] = len(p.expanding)
+	p.expanding = append(p.expanding, key)
+	value, err := p.expandString(value)
+	p.expanding = p.expanding[:len(p.expanding)-1]
+	delete(p.chain, key)
+	if err != nil {
+		// Errors are located at the innermost key that failed to expand.
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			e, _ := p.result.Lookup(key)
+			err = &ParseError{File: e.File, Line: e.Line, Column: e.Column, Text: e.Raw, Err: err}
+		}
+		p.failed[key] = err
+		return "", err
+	}
+
+	p.result.Vars[key] = value
+	p.result.Entries[p.result.index[key]].Value = value
+	p.expanded[key] = true
+	return value, nil
+}
+
+// declare adds e to the result. A key that is already declared keeps its
+// place in the order, and e records the declarations it overrides.
+func (p *Parser) declare(e Entry) {
+	p.result.Vars[e.Key] = e.Value
+	p.expanded[e.Key] = e.literal
+	i, ok := p.result.index[e.Key]
+	if !ok {
+		p.result.index[e.Key] = len(p.result.Entries)
+		p.result.Entries = append(p.result.Entries, e)
+		return
+	}
+	prev := p.result.Entries[i]
+	e.Overrides = append(prev.Overrides, prev)
+	e.Overrides[len(e.Overrides)-1].Overrides = nil
+	p.result.Entries[i] = e
+}
+
+// expandString replaces the references in s with their values and resolves
+// the \\ and \$ escapes.
+//
+// References follow the shell's parameter expansion:
+//
+//	$NAME, ${NAME}  value of NAME, see UndefinedPolicy if NAME is not set
+//	${NAME:-word}   word if NAME is not set or empty
+//	${NAME-word}    word if NAME is not set
+//	${NAME:=word}   word if NAME is not set or empty, also assigned to NAME
+//	${NAME=word}    word if NAME is not set, also assigned to NAME
+//	${NAME:?word}   an error with message word if NAME is not set or empty
+//	${NAME?word}    an error with message word if NAME is not set
+//	${NAME:+word}   word if NAME is set and not empty, otherwise empty
+//	${NAME+word}    word if NAME is set, otherwise empty
+//	$(command)      output of command, see WithCommands
+//
+// The word is itself expanded, and may hold nested references. A reference
+// from a key to itself, as in PORT=${PORT:-8080}, refers to the earlier
+// declaration of the key, or else to the lookup function.
+func (p *Parser) expandString(s string) (string, error) {
+	i := strings.IndexAny(s, `\$`)
+	if i < 0 {
+		return s, nil
 	}
 
-	return nil
+	// s is read once, copying the text between references as it goes.
+	var b strings.Builder
+	b.Grow(len(s))
+	for i >= 0 {
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		n := 1
+		switch {
+		case len(s) == 1:
+			b.WriteByte(s[0])
+		case s[0] == '\\':
+			// Only \\ and \$ are escapes, any other backslash is kept.
+			if s[1] == '\\' || s[1] == '$' {
+				b.WriteByte(s[1])
+				n = 2
+			} else {
+				b.WriteByte('\\')
+			}
+		default:
+			ref, value, err := p.reference(s)
+			if err != nil {
+				return "", err
+			}
+			if ref == "" {
+				// Not a reference, the $ is kept.
+				b.WriteByte('$')
+			} else {
+				b.WriteString(value)
+				n = len(ref)
+			}
+		}
+		s = s[n:]
+		i = strings.IndexAny(s, `\$`)
+	}
+	b.WriteString(s)
+	return b.String(), nil
 }
 
-func (p *Parser) expand() error {
-	return nil
+// reference resolves the reference at the start of s, which starts with $. It
+// returns the text of the reference along with its value, or an empty ref if
+// s does not start with a reference.
+func (p *Parser) reference(s string) (ref, value string, err error) {
+	switch c := s[1]; {
+	case c == '{':
+		return p.bracedReference(s)
+	case c == '(':
+		end := commandEnd(s)
+		if end < 0 {
+			return "", "", nil
+		}
+		ref = s[:end+1]
+		value, err = p.runCommand(ref, s[2:end])
+		return ref, value, err
+	case c == '_' || isLetter(c):
+		n := 2
+		for n < len(s) && (s[n] == '_' || isLetter(s[n]) || isDigit(s[n])) {
+			n++
+		}
+		ref = s[:n]
+		value, err = p.substitute(ref, s[1:n], "", "")
+		return ref, value, err
+	default:
+		return "", "", nil
+	}
+}
+
+// bracedReference resolves a reference such as ${NAME} or ${NAME:-word} at the
+// start of s. The word may hold nested references, as in ${A:-${B}}.
+func (p *Parser) bracedReference(s string) (ref, value string, err error) {
+	n := 2
+	for n < len(s) && (s[n] == '_' || isLetter(s[n]) || isDigit(s[n])) {
+		n++
+	}
+	name := s[2:n]
+	if name == "" || n == len(s) {
+		return "", "", nil
+	}
+	if s[n] == '}' {
+		ref = s[:n+1]
+		value, err = p.substitute(ref, name, "", "")
+		return ref, value, err
+	}
+
+	opStart := n
+	if s[n] == ':' {
+		n++
+	}
+	if n == len(s) || strings.IndexByte("-=?+", s[n]) < 0 {
+		return "", "", nil
+	}
+	n++
+	op := s[opStart:n]
+
+	// Find the brace that closes the reference, skipping the \\ and \$ escapes
+	// and the braces of nested references.
+	depth := 0
+	for end := n; end < len(s); end++ {
+		switch s[end] {
+		case '\\':
+			if end+1 < len(s) && (s[end+1] == '\\' || s[end+1] == '$') {
+				end++
+			}
+		case '$':
+			if end+1 < len(s) && s[end+1] == '{' {
+				depth++
+				end++
+			}
+		case '}':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			ref = s[:end+1]
+			value, err = p.substitute(ref, name, op, s[n:end])
+			return ref, value, err
+		}
+	}
+	return "", "", nil
+}
+
+// commandEnd returns the index of the paren that closes the command
+// substitution at the start of s, or -1 if it is not closed. Parens inside
+// quotes or nested substitutions, as in $(printf 'a)b') or $(echo $(date)), do
+// not close it.
+func commandEnd(s string) int {
+	var (
+		depth int
+		quote byte
+	)
+	for end := 2; end < len(s); end++ {
+		c := s[end]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			end++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return end
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// substitute returns the value of the reference ref to name, applying the
+// operator op to word. See expandString for the supported operators.
+//
+// Keys declared in the parsed files take precedence over the lookup function.
+func (p *Parser) substitute(ref, name, op, word string) (string, error) {
+	var (
+		value string
+		set   bool
+	)
+	self := len(p.expanding) > 0 && p.expanding[len(p.expanding)-1] == name
+	if self {
+		v, ok, err := p.previous(name)
+		if err != nil {
+			return "", err
+		}
+		value, set = v, ok
+	} else if _, ok := p.result.Vars[name]; ok {
+		v, err := p.expand(name)
+		if err != nil {
+			return "", err
+		}
+		value, set = v, true
+	} else if p.lookup != nil {
+		value, set = p.lookup(name)
+	}
+
+	unset := !set
+	if strings.HasPrefix(op, ":") {
+		unset = !set || value == ""
+	}
+
+	switch strings.TrimPrefix(op, ":") {
+	case "-":
+		if unset {
+			return p.expandString(word)
+		}
+	case "=":
+		if unset {
+			v, err := p.expandString(word)
+			if err != nil || self {
+				// A key referencing itself is assigned its own value.
+				return v, err
+			}
+			p.declare(Entry{Key: name, Raw: word, Value: v, literal: true})
+			return v, nil
+		}
+	case "?":
+		if unset {
+			msg, err := p.expandString(word)
+			if err != nil {
+				return "", err
+			}
+			if msg == "" {
+				msg = "parameter null or not set"
+			}
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+	case "+":
+		if unset {
+			return "", nil
+		}
+		return p.expandString(word)
+	default:
+		if !set {
+			switch p.undefined {
+			case UndefinedEmpty:
+				return "", nil
+			case UndefinedKeep:
+				return ref, nil
+			}
+			return "", fmt.Errorf("environment variable not set: %q", name)
+		}
+	}
+	return value, nil
+}
+
+// previous returns the value that a reference from key to itself refers to,
+// as in PORT=${PORT:-8080}: the value of the declaration of key that the one
+// being expanded overrides, or else the value of the lookup function.
+func (p *Parser) previous(key string) (string, bool, error) {
+	e, _ := p.result.Lookup(key)
+	i := len(e.Overrides) - 1 - p.shadowed[key]
+	if i < 0 {
+		if p.lookup == nil {
+			return "", false, nil
+		}
+		value, ok := p.lookup(key)
+		return value, ok, nil
+	}
+	prev := e.Overrides[i]
+	if prev.literal {
+		return prev.Raw, true, nil
+	}
+	p.shadowed[key]++
+	defer func() { p.shadowed[key]-- }()
+	value, err := p.expandString(prev.Raw)
+	return value, true, err
 }
 
 type LineParser struct {
@@ -99,14 +705,38 @@ type LineParser struct {
 	position int
 	cursor   int
 	curr     byte
+	quote    byte
+	// bare is true for a key without =, as in KEY.
+	bare bool
+	// heredoc is set for a key followed by a heredoc, as in KEY<<EOF. The
+	// lines of its value are read by the caller.
+	heredoc *heredoc
+	// dialect and keyGrammar decide the syntax of the line.
+	dialect    Dialect
+	keyGrammar KeyGrammar
+	// keyStart, keyEnd, valueStart and valueEnd locate the key and the value,
+	// including its quotes, in the line.
+	keyStart   int
+	keyEnd     int
+	valueStart int
+	valueEnd   int
 }
 
+// NewLineParser returns a parser for a line in the default dialect.
 func NewLineParser(line []byte) *LineParser {
-	p := &LineParser{line: line}
+	p := &LineParser{line: line, dialect: DialectGenv}
 	p.consume()
 	return p
 }
 
+// newLineParser returns a LineParser configured like p.
+func (p *Parser) newLineParser(line []byte) *LineParser {
+	lp := NewLineParser(line)
+	lp.dialect = p.dialect
+	lp.keyGrammar = p.keyGrammar
+	return lp
+}
+
 func (p *LineParser) consume() {
 	if p.cursor >= len(p.line) {
 		p.curr = 0
@@ -124,19 +754,31 @@ func (p *LineParser) parse() (key, value string, err error) {
 		return key, value, nil
 	}
 
-	p.skipWhitespace()
+	if p.dialect.Export {
+		p.skipExport()
+	}
 
 	key, err = p.consumeKey()
 	if err != nil {
 		return key, value, err
 	}
 
-	p.skipWhitespace()
+	if p.dialect.TrimSpace {
+		p.skipWhitespace()
+	}
 
 	if p.curr == '=' {
 		p.consume()
+	} else if p.dialect.Heredocs && bytes.HasPrefix(p.line[p.position:], []byte("<<")) {
+		return key, value, p.consumeHeredoc()
+	} else if p.eol() || p.atComment() {
+		// A bare key takes its value from the environment, see
+		// WithRequireBareKeys.
+		p.bare = true
+		p.valueStart, p.valueEnd = p.keyEnd, p.keyEnd
+		return key, value, nil
 	} else {
-		return key, value, fmt.Errorf("unexpected char: %s, expected %s", string(p.curr), "=")
+		return key, value, p.errorf("unexpected char: %s, expected %s", string(p.curr), "=")
 	}
 
 	value, err = p.consumeValue()
@@ -147,34 +789,174 @@ func (p *LineParser) parse() (key, value string, err error) {
 	return key, value, nil
 }
 
+// errorf returns an error at the current position.
+func (p *LineParser) errorf(format string, args ...any) error {
+	return &ParseError{Err: fmt.Errorf(format, args...), offset: p.position}
+}
+
 func (p *LineParser) skipWhitespace() {
-	for p.curr == ' ' {
+	for p.curr == ' ' || p.curr == '\t' {
+		p.consume()
+	}
+}
+
+// skipExport skips a leading export keyword, so that files meant to be
+// sourced by a shell load into the same keys the shell would see.
+func (p *LineParser) skipExport() {
+	const keyword = "export"
+	rest := p.line[p.position:]
+	if !bytes.HasPrefix(rest, []byte(keyword)) || len(rest) == len(keyword) {
+		return
+	}
+	if next := rest[len(keyword)]; next != ' ' && next != '\t' {
+		return
+	}
+	for range keyword {
 		p.consume()
 	}
+	p.skipWhitespace()
 }
 
 func (p *LineParser) consumeKey() (string, error) {
 	start := p.position
-	for {
-		if p.curr == 0 {
-			return "", errors.New("unexpected end of line")
-		}
-		if p.curr == '=' {
-			break
-		}
+	p.keyStart = start
+	if p.curr == 0 {
+		return "", p.errorf("unexpected end of line")
+	}
+	if !p.keyGrammar.isKeyStart(p.curr) {
+		return "", p.errorf("unexpected char: %s, expected key", string(p.curr))
+	}
+	for p.curr != 0 && p.keyGrammar.isKeyChar(p.curr) {
 		p.consume()
 	}
+	p.keyEnd = p.position
 	return string(p.line[start:p.position]), nil
 }
 
+func (p *LineParser) eol() bool {
+	return p.position >= len(p.line)
+}
+
 func (p *LineParser) consumeValue() (string, error) {
+	if p.dialect.TrimSpace {
+		p.skipWhitespace()
+	}
+	p.valueStart = p.position
+
+	if p.dialect.Quotes {
+		switch p.curr {
+		case '\'', '"':
+			return p.consumeQuotedValue()
+		case '`':
+			if p.dialect.Backticks {
+				return p.consumeQuotedValue()
+			}
+		}
+	}
+
 	start := p.position
+	for !p.eol() && !p.atComment() {
+		p.consume()
+	}
+
+	raw := p.line[start:p.position]
+	if !p.dialect.TrimSpace {
+		p.valueEnd = p.position
+		return string(raw), nil
+	}
+	value := bytes.TrimSpace(raw)
+	p.valueStart = start + len(raw) - len(bytes.TrimLeftFunc(raw, unicode.IsSpace))
+	p.valueEnd = p.valueStart + len(value)
+	return string(value), nil
+}
+
+// atComment reports whether the current char starts an inline comment. See
+// InlineComments for where comments start.
+func (p *LineParser) atComment() bool {
+	if p.curr != '#' {
+		return false
+	}
+	switch p.dialect.InlineComments {
+	case InlineCommentsAnyHash:
+		return true
+	case InlineCommentsAfterSpace:
+		if p.position == 0 {
+			return false
+		}
+		prev := p.line[p.position-1]
+		return prev == ' ' || prev == '\t'
+	default:
+		return false
+	}
+}
+
+// consumeQuotedValue reads a value wrapped in single quotes, double quotes or
+// backticks.
+//
+// Single-quoted and backtick values are taken verbatim. Double-quoted values
+// process the escapes of the dialect, see Escapes. When the value is expanded,
+// the \\ and \$ escapes are kept as they are and resolved during expansion,
+// so that an escaped $ is never expanded.
+func (p *LineParser) consumeQuotedValue() (string, error) {
+	p.quote = p.curr
+	open := p.position
+	p.consume()
+
+	var b strings.Builder
 	for {
-		if p.curr == 0 {
+		if p.eol() {
+			return "", &ParseError{Err: errUnterminatedQuote, offset: open}
+		}
+		if p.curr == p.quote {
+			p.consume()
+			p.valueEnd = p.position
 			break
 		}
+		if p.curr == '\\' && p.quote == '"' && p.dialect.Escapes != EscapesNone {
+			p.consume()
+			if p.eol() {
+				return "", &ParseError{Err: errUnterminatedQuote, offset: open}
+			}
+			p.unescape(&b)
+			p.consume()
+			continue
+		}
+		b.WriteByte(p.curr)
 		p.consume()
 	}
 
-	return strings.TrimSpace(strings.Trim(string(p.line[start:p.position]), "\"")), nil
+	p.skipWhitespace()
+	if !p.eol() && p.curr != '#' {
+		return "", p.errorf("unexpected char: %s, expected end of line", string(p.curr))
+	}
+
+	return b.String(), nil
+}
+
+// unescape writes the escape of the current char to b.
+func (p *LineParser) unescape(b *strings.Builder) {
+	c := p.curr
+	switch {
+	case c == 'n':
+		b.WriteByte('\n')
+	case c == 'r':
+		b.WriteByte('\r')
+	case p.dialect.Escapes == EscapesNewlines:
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	case c == '\\' || c == '$':
+		if p.dialect.Expand {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	case p.dialect.Escapes == EscapesAll:
+		b.WriteByte(c)
+	case c == 't':
+		b.WriteByte('\t')
+	case c == '"':
+		b.WriteByte('"')
+	default:
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	}
 }
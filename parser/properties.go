@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// parseProperties declares the keys of the .properties file read from r. See
+// FormatProperties.
+func (p *Parser) parseProperties(src source, r io.Reader) error {
+	var (
+		scanner = newLineReader(r)
+		lineNum int
+	)
+	for scanner.Scan() {
+		lineNum++
+		text := string(scanner.Bytes())
+		line := strings.TrimLeft(text, " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// A line ending in an odd number of backslashes continues on the
+		// next line, without its leading whitespace.
+		start := lineNum
+		indent := len(text) - len(line)
+		for continues(line) {
+			line = line[:len(line)-1]
+			if !scanner.Scan() {
+				break
+			}
+			lineNum++
+			line += strings.TrimLeft(string(scanner.Bytes()), " \t\f")
+		}
+
+		key, value, err := p.splitProperty(line)
+		if err != nil {
+			perr := &ParseError{File: src.name, Line: start, Column: indent + 1, Text: text, Err: err}
+			if !p.report(perr) {
+				return perr
+			}
+			continue
+		}
+		p.declareValue(key, value, src.name, start, indent+1)
+	}
+	return scanner.Err()
+}
+
+// splitProperty splits a logical line of a .properties file into its key and
+// value. The key ends at the first unescaped =, : or whitespace.
+func (p *Parser) splitProperty(line string) (key, value string, err error) {
+	i := 0
+	for i < len(line) && strings.IndexByte("=: \t\f", line[i]) < 0 {
+		if line[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	i = min(i, len(line))
+	rest := strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	name, err := unescapeProperty(line[:i])
+	if err != nil {
+		return "", "", err
+	}
+	if value, err = unescapeProperty(rest); err != nil {
+		return "", "", err
+	}
+	if key, err = p.envKey("", name); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// continues reports whether line ends in an odd number of backslashes.
+func continues(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+var errInvalidUnicodeEscape = errors.New("invalid \\uXXXX escape")
+
+// unescapeProperty resolves the escapes of a .properties file: \t, \n, \r,
+// \f and \uXXXX, including surrogate pairs. Any other escaped char is the
+// char itself.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, err := unicodeEscape(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			i += 4
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+				if r2, err := unicodeEscape(s[i+3:]); err == nil {
+					if pair := utf16.DecodeRune(r, r2); pair != unicode.ReplacementChar {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// unicodeEscape returns the rune of the 4 hex digits at the start of s.
+func unicodeEscape(s string) (rune, error) {
+	if len(s) < 4 {
+		return 0, fmt.Errorf("%w: \\u%s", errInvalidUnicodeEscape, s)
+	}
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%w: \\u%s", errInvalidUnicodeEscape, s[:4])
+	}
+	return rune(n), nil
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParserProperties(t *testing.T) {
+	result, err := NewParser("../testdata/formats/app.properties").Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := map[string]string{
+		"APP_NAME":     "service",
+		"APP_PORT":     "8080",
+		"APP_GREETING": "Hello, world",
+		"APP_PATH":     `C:\data\app`,
+		"APP_UNICODE":  "café 😀",
+		"APP_TAB":      "a\tb",
+		"EMPTY":        "",
+		"SPACED_KEY":   "value with spaces",
+		"ESCAPED_KEY":  "x",
+	}
+	assertVars(t, result, want)
+
+	e, _ := result.Lookup("APP_PATH")
+	if e.Line != 7 {
+		t.Fatalf("want line 7, got %d", e.Line)
+	}
+}
+
+func TestParserPropertiesErrors(t *testing.T) {
+	_, err := NewBytesParser("app.properties", []byte("a=1\n  bad=\\u12\n")).Parse()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("want *ParseError, got %v", err)
+	}
+	if perr.Line != 2 || perr.Column != 3 || !errors.Is(err, errInvalidUnicodeEscape) {
+		t.Fatalf("want invalid escape at 2:3, got %v", err)
+	}
+}
+
+func TestParserFlattenedKeysRelaxed(t *testing.T) {
+	files := map[string]string{
+		"app.properties": "db.host=localhost\n",
+		"app.ini":        "[db]\nmax-conns=10\n",
+		"app.json":       `{"db.port": 5432}`,
+	}
+	want := map[string]string{
+		"app.properties": "DB_HOST",
+		"app.ini":        "DB_MAX_CONNS",
+		"app.json":       "DB_PORT",
+	}
+	for name, content := range files {
+		result, err := NewBytesParser(name, []byte(content)).With(WithKeyGrammar(KeyGrammarRelaxed)).Parse()
+		if err != nil {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+		if _, ok := result.Vars[want[name]]; !ok || len(result.Vars) != 1 {
+			t.Fatalf("%s: want %s, got %v", name, want[name], result.Vars)
+		}
+	}
+}
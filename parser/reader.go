@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// bom is the UTF-8 byte order mark some editors write at the start of a file.
+var bom = []byte("\xef\xbb\xbf")
+
+// lineReader reads lines like a bufio.Scanner, without a limit on the length
+// of a line. It drops the line terminators, \n or \r\n, and a byte order mark
+// at the start of the input.
+type lineReader struct {
+	r    *bufio.Reader
+	line []byte
+	n    int
+	err  error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// Scan reads the next line and reports whether there was one.
+func (lr *lineReader) Scan() bool {
+	if lr.err != nil {
+		return false
+	}
+	line, err := lr.r.ReadBytes('\n')
+	if err != nil {
+		lr.err = err
+		if err != io.EOF || len(line) == 0 {
+			return false
+		}
+	}
+	if lr.n == 0 {
+		line = bytes.TrimPrefix(line, bom)
+	}
+	lr.n++
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	lr.line = bytes.TrimSuffix(line, []byte("\r"))
+	return true
+}
+
+// Bytes returns the last line read.
+func (lr *lineReader) Bytes() []byte {
+	return lr.line
+}
+
+// Err returns the first error other than io.EOF.
+func (lr *lineReader) Err() error {
+	if lr.err == io.EOF {
+		return nil
+	}
+	return lr.err
+}
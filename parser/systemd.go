@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseSystemd declares the keys of the systemd EnvironmentFile read from r.
+// See FormatSystemd.
+func (p *Parser) parseSystemd(src source, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	data = bytes.ReplaceAll(bytes.TrimPrefix(data, bom), []byte("\r\n"), []byte("\n"))
+
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '#' || c == ';':
+			// A backslash at the end of a comment continues it.
+			for i < len(data) && data[i] != '\n' {
+				if data[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			continue
+		}
+
+		// Lines without = are ignored, as systemd does.
+		at := i
+		for i < len(data) && data[i] != '=' && data[i] != '\n' {
+			i++
+		}
+		if i == len(data) || data[i] == '\n' {
+			continue
+		}
+		key := strings.TrimRight(string(data[at:i]), " \t")
+		value, next, err := systemdValue(data, i+1)
+		i = next
+		var perr *ParseError
+		switch {
+		case errors.As(err, &perr):
+			perr = locateOffset(perr.Err, src.name, data, perr.offset)
+		case !p.keyGrammar.valid(key):
+			perr = locateOffset(fmt.Errorf("invalid key: %q", key), src.name, data, at)
+		}
+		if perr != nil {
+			if !p.report(perr) {
+				return perr
+			}
+			continue
+		}
+		line, column := lineColumn(data, at)
+		p.declareValue(key, value, src.name, line, column)
+	}
+	return nil
+}
+
+// systemdValue reads the value that starts at data[i], and returns it with the
+// offset of the next line.
+//
+// As in systemd, quoted and unquoted parts of a value are concatenated, and
+// whitespace around the value and between quoted parts is dropped. A
+// backslash at the end of a line continues the value on the next line. Outside
+// of quotes a backslash escapes any char. In double quotes it escapes ", \, `
+// and $, and is kept before any other char. Values are never expanded, and #
+// does not start a comment.
+func systemdValue(data []byte, i int) (value string, next int, err error) {
+	var (
+		b strings.Builder
+		// quoted is true after a quoted part and before any unquoted char,
+		// where whitespace is dropped and quotes open a quoted part.
+		quoted = true
+		// trailing counts the whitespace at the end of b, which is dropped
+		// unless more unquoted chars follow.
+		trailing int
+	)
+	for i < len(data) && data[i] != '\n' {
+		c := data[i]
+		switch {
+		case quoted && (c == ' ' || c == '\t'):
+			i++
+		case quoted && c == '\'':
+			end := bytes.IndexByte(data[i+1:], '\'')
+			if end < 0 {
+				return "", len(data), &ParseError{Err: errUnterminatedQuote, offset: i}
+			}
+			b.Write(data[i+1 : i+1+end])
+			i += end + 2
+			trailing = 0
+		case quoted && c == '"':
+			open := i
+			for i++; ; i++ {
+				if i == len(data) {
+					return "", len(data), &ParseError{Err: errUnterminatedQuote, offset: open}
+				}
+				if data[i] == '"' {
+					break
+				}
+				if data[i] == '\\' && i+1 < len(data) {
+					i++
+					switch data[i] {
+					case '\n':
+						continue
+					case '"', '\\', '`', '$':
+					default:
+						b.WriteByte('\\')
+					}
+				}
+				b.WriteByte(data[i])
+			}
+			i++
+			trailing = 0
+		case c == '\\':
+			i++
+			if i == len(data) {
+				break
+			}
+			if data[i] != '\n' {
+				b.WriteByte(data[i])
+				trailing = 0
+			}
+			quoted = false
+			i++
+		default:
+			b.WriteByte(c)
+			quoted = false
+			if c == ' ' || c == '\t' {
+				trailing++
+			} else {
+				trailing = 0
+			}
+			i++
+		}
+	}
+	return b.String()[:b.Len()-trailing], i, nil
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParserSystemd(t *testing.T) {
+	result, err := NewParser("../testdata/systemd/app.env").With(WithFormat(FormatSystemd)).Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := map[string]string{
+		"PLAIN":            "plain value",
+		"SPACED":           "value",
+		"DOUBLE":           `double "quoted" $HOME \n`,
+		"SINGLE":           `single "quoted" $HOME`,
+		"JOINED":           "abc",
+		"CONTINUED":        "first second",
+		"QUOTED_CONTINUED": "first second",
+		"MULTILINE":        "line one\nline two",
+		"ESCAPED":          "  leading",
+		"HASH":             "a # b",
+		"EMPTY":            "",
+	}
+	assertVars(t, result, want)
+
+	e, _ := result.Lookup("QUOTED_CONTINUED")
+	if e.Line != 11 || e.Column != 1 {
+		t.Fatalf("want 11:1, got %d:%d", e.Line, e.Column)
+	}
+}
+
+func TestParserSystemdErrors(t *testing.T) {
+	testcases := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"A=1\nB=\"unterminated\n", 2, 3},
+		{"A=1\nB='unterminated\n", 2, 3},
+		{"A=1\n  1B=x\n", 2, 3},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewBytesParser("app.env", []byte(tc.input)).With(WithFormat(FormatSystemd)).Parse()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: want *ParseError, got %v", tc.input, err)
+		}
+		if perr.Line != tc.line || perr.Column != tc.column {
+			t.Fatalf("%q: want %d:%d, got %d:%d: %v", tc.input, tc.line, tc.column, perr.Line, perr.Column, err)
+		}
+	}
+}